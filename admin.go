@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/currency"
+)
+
+var adminUsage = `Chaindata commands, run on the -bc directory while the node is stopped:
+  init --genesis <file>               create the chain from a JSON genesis file
+  export [--from N] [--to N] <file>   write blocks to file in RLP, - for stdout
+  import <file>                       insert the RLP blocks of file, - for stdin
+  inspect                             report the head, the database size and integrity problems
+  rewind <N>                          delete the blocks above N and rebuild the state at N
+`
+
+// withBlockChain runs fn on the blockchain of the -bc path and closes it
+func withBlockChain(fn func(bc *blockchain.BlockChain) error) error {
+	bc, err := currency.OpenBlockChain()
+	if err != nil {
+		return fmt.Errorf("failed to open the chaindata, is the node running? %v", err)
+	}
+	defer bc.Close()
+	return fn(bc)
+}
+
+// openInput opens a file, or stdin for -
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+func printHead(bc *blockchain.BlockChain) {
+	head := bc.CurrentBlock()
+	fmt.Printf("Head: #%d [%x]\n", head.Number().Uint64(), head.Hash().Bytes())
+}
+
+func initChain(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	genesisPath := fs.String("genesis", "", "JSON genesis file")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *genesisPath == "" {
+		return usageError("init takes a --genesis file")
+	}
+	genesis, err := blockchain.LoadGenesis(*genesisPath)
+	if err != nil {
+		return err
+	}
+
+	bc, err := blockchain.NewWithGenesis(currency.ChainDataPath(), genesis)
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+	if bc.GetBlockByNumber(0).Hash() != genesis.ToBlock().Hash() {
+		return errors.New("chaindata already holds a chain created before genesis files")
+	}
+	printHead(bc)
+	return nil
+}
+
+func exportChain(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	from := fs.Uint64("from", 0, "first exported block")
+	to := fs.Int64("to", -1, "last exported block (default: head)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("export takes the output file")
+	}
+
+	return withBlockChain(func(bc *blockchain.BlockChain) error {
+		last := bc.CurrentBlock().Number().Uint64()
+		if *to >= 0 && uint64(*to) < last {
+			last = uint64(*to)
+		}
+		var out io.Writer = os.Stdout
+		if positional[0] != "-" {
+			f, err := os.Create(positional[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		w := bufio.NewWriter(out)
+		if err := bc.ExportN(w, *from, last); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported blocks #%d to #%d\n", *from, last)
+		return nil
+	})
+}
+
+func importChain(args []string) error {
+	positional, err := parseArgs(flag.NewFlagSet("import", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("import takes the input file")
+	}
+	in, err := openInput(positional[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return withBlockChain(func(bc *blockchain.BlockChain) error {
+		imported, err := bc.Import(bufio.NewReader(in))
+		fmt.Printf("Imported %d blocks\n", imported)
+		printHead(bc)
+		return err
+	})
+}
+
+func inspectChain(args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("inspect", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	return withBlockChain(func(bc *blockchain.BlockChain) error {
+		printHead(bc)
+		fmt.Printf("Blocks: %d\n", bc.CurrentBlock().Number().Uint64()+1)
+
+		stats, err := bc.InspectDatabase()
+		if err != nil {
+			return err
+		}
+		total := uint64(0)
+		fmt.Printf("\n%-20s %12s %12s\n", "Data", "Entries", "Size")
+		for _, stat := range stats {
+			fmt.Printf("%-20s %12d %12s\n", stat.Name, stat.Count, formatSize(stat.Size))
+			total += stat.Size
+		}
+		fmt.Printf("%-20s %12s %12s\n", "Total", "", formatSize(total))
+
+		problems := bc.CheckIntegrity()
+		if len(problems) == 0 {
+			fmt.Println("\nNo integrity problems found")
+			return nil
+		}
+		fmt.Printf("\n%d integrity problems:\n", len(problems))
+		for _, problem := range problems {
+			fmt.Println("  " + problem.Error())
+		}
+		return fmt.Errorf("chaindata has %d integrity problems", len(problems))
+	})
+}
+
+func rewindChain(args []string) error {
+	positional, err := parseArgs(flag.NewFlagSet("rewind", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("rewind takes the number of the new head")
+	}
+	head, err := strconv.ParseUint(positional[0], 10, 64)
+	if err != nil {
+		return usageError("invalid block number " + positional[0])
+	}
+
+	return withBlockChain(func(bc *blockchain.BlockChain) error {
+		if current := bc.CurrentBlock().Number().Uint64(); head > current {
+			return fmt.Errorf("block #%d is ahead of the head #%d", head, current)
+		}
+		if err := bc.SetHead(head); err != nil {
+			return err
+		}
+		printHead(bc)
+		return nil
+	})
+}
+
+func formatSize(size uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
@@ -1,6 +1,8 @@
 package blockchain
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -8,7 +10,6 @@ import (
 	"math/big"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"bitbucket.org/ventureslash/go-ibft"
 	"bitbucket.org/ventureslash/go-slash-currency/rawdb"
@@ -19,11 +20,22 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
-var verbose = flag.Bool("verbose-blockchain", false, "print blockchain info level logs")
+var (
+	verbose      = flag.Bool("verbose-blockchain", false, "print blockchain info level logs")
+	stateHistory = flag.Uint64("state-history", 0, "number of past blocks whose state is kept for historical queries (0 keeps a full archive)")
+	checkpoints  = flag.Uint64("state-checkpoints", 1024, "number of blocks between the full states stored to bound the rebuild of historical states (0 disables them)")
+
+	errStatePruned = errors.New("historical state has been pruned")
+)
+
+// importBatchSize is the number of blocks Import holds in memory at once
+const importBatchSize = 256
 
 // BlockChain is the structure managing and storing blocks
 type BlockChain struct {
 	db           *leveldb.DB
+	genesis      *Genesis
+	config       *types.ChainConfig
 	genesisBlock *types.Block
 	currentBlock atomic.Value
 	mu           sync.RWMutex // global mutex for locking chain operations
@@ -32,8 +44,24 @@ type BlockChain struct {
 	debug        *logger.Logger
 }
 
-// New resturns a new instance of Blockchain
+// New resturns a new instance of Blockchain. A new chain starts from the
+// genesis of the -genesis file, or the one of the main network.
 func New(file string) (*BlockChain, error) {
+	var genesis *Genesis
+	if *genesisPath != "" {
+		var err error
+		if genesis, err = LoadGenesis(*genesisPath); err != nil {
+			return nil, err
+		}
+	}
+	return NewWithGenesis(file, genesis)
+}
+
+// NewWithGenesis returns the blockchain stored in file, creating it from
+// genesis when it is empty. It fails when the stored chain was created from
+// another genesis. A nil genesis opens the stored chain whatever its genesis,
+// or creates one from the main network genesis.
+func NewWithGenesis(file string, genesis *Genesis) (*BlockChain, error) {
 	db, err := rawdb.InitDB(file)
 	if err != nil {
 		return nil, err
@@ -41,14 +69,18 @@ func New(file string) (*BlockChain, error) {
 
 	bc := &BlockChain{
 		db:    db,
-		state: state.New(),
 		debug: logger.Init("BlockChain", *verbose, false, ioutil.Discard),
 	}
 
-	bc.genesisBlock = bc.readOrCreateGenesisBlock()
+	if err := bc.setupGenesis(genesis); err != nil {
+		db.Close()
+		return nil, err
+	}
 	if err := bc.loadLastState(); err != nil {
+		db.Close()
 		return nil, err
 	}
+	registerDBMetrics(db)
 
 	return bc, nil
 }
@@ -73,8 +105,15 @@ func (bc *BlockChain) loadLastState() error {
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock.Store(currentBlock)
 
+	// The state history of the blocks folded into the snapshot of a pruning
+	// node must not be written back
+	pruned := int64(-1)
+	if snapshot := rawdb.ReadStateSnapshot(bc.db); snapshot != nil {
+		pruned = snapshot.Number.Int64()
+	}
+
 	// Apply each transactions from each blocks to restore the state
-	bc.state = state.New()
+	bc.state = state.New(bc.config)
 	for i := uint64(0); i <= currentBlock.Number().Uint64(); i++ {
 		bc.debug.Infof("loading tx from block #%d", i)
 		b := bc.GetBlockByNumber(i)
@@ -82,29 +121,101 @@ func (bc *BlockChain) loadLastState() error {
 			return fmt.Errorf("Failed to load block #%d", i)
 		}
 		bc.state.ProcessBlock(b)
+		// Nodes created before state diffs and transaction indexes were stored
+		// are upgraded here
+		rawdb.WriteTxLookupEntries(bc.db, b)
+		diff := bc.state.CommitDiff()
+		charges := bc.state.CommitCharges()
+		if len(charges) > 0 && !rawdb.HasDemurrageCharges(bc.db, b.Hash(), i) {
+			rawdb.WriteDemurrageCharges(bc.db, b.Hash(), i, charges)
+		}
+		rawdb.WriteSupplyStats(bc.db, b.Hash(), i, bc.state.Stats())
+		mints := bc.state.CommitMints()
+		if int64(i) <= pruned {
+			continue
+		}
+		if !rawdb.HasStateDiff(bc.db, b.Hash(), i) {
+			rawdb.WriteStateDiff(bc.db, b.Hash(), i, diff)
+		}
+		if len(mints) > 0 {
+			rawdb.WriteBlockMints(bc.db, b.Hash(), i, mints)
+		}
+		if !rawdb.HasStateCheckpoint(bc.db, b.Hash(), i) {
+			bc.writeCheckpoint(b.Hash(), i)
+		}
 	}
+	bc.pruneState(currentBlock.Number().Uint64())
 
 	return nil
 }
 
-func (bc *BlockChain) readOrCreateGenesisBlock() *types.Block {
-	genesis := bc.GetBlockByNumber(0)
-	if genesis == nil {
+// setupGenesis loads the genesis of the stored chain, or stores genesis for a
+// new one, and sets the rules of the chain
+func (bc *BlockChain) setupGenesis(genesis *Genesis) error {
+	stored := rawdb.ReadGenesis(bc.db)
+	if len(stored) > 0 {
+		storedGenesis := &Genesis{}
+		if err := json.Unmarshal(stored, storedGenesis); err != nil {
+			return fmt.Errorf("invalid stored genesis: %v", err)
+		}
+		if genesis != nil && genesis.ToBlock().Hash() != storedGenesis.ToBlock().Hash() {
+			return errGenesisMismatch
+		}
+		genesis = storedGenesis
+	} else {
+		if genesis == nil {
+			genesis = DefaultGenesis()
+		}
+		data, err := json.Marshal(genesis)
+		if err != nil {
+			return err
+		}
+		rawdb.WriteGenesis(bc.db, data)
+	}
+	bc.genesis = genesis
+	bc.config = genesis.Config
+	// Transactions are signed for the chain ID of the genesis
+	types.ChainID = genesis.Config.ChainID
+
+	block := bc.GetBlockByNumber(0)
+	if block == nil {
 		bc.debug.Info("No genesis block, creating one.")
-		genesis = types.NewBlock(&types.Header{
-			Number:     big.NewInt(0),
-			ParentHash: ibft.Hash{},
-			Time:       big.NewInt(time.Now().Unix()),
-		}, types.Transactions{})
+		block = genesis.ToBlock()
+		bc.writeGenesisBlock(block)
+	} else if len(stored) == 0 {
+		bc.debug.Warning("Chain created before genesis files, applying the rules of the genesis to it")
+	}
+	bc.genesisBlock = block
+	bc.currentBlock.Store(block)
+	return nil
+}
 
-		rawdb.WriteBlock(bc.db, genesis)
-		rawdb.WriteReceipts(bc.db, genesis.Hash(), genesis.Number().Uint64(), nil)
-		rawdb.WriteBlockHash(bc.db, genesis.Hash(), genesis.Number().Uint64())
-		rawdb.WriteHeadBlockHash(bc.db, genesis.Hash())
+// writeGenesisBlock stores a genesis block as the head of the chain and
+// resets the state to its allocations
+func (bc *BlockChain) writeGenesisBlock(genesis *types.Block) {
+	bc.state = state.New(bc.config)
+	receipts, _ := bc.state.ProcessBlock(genesis)
+	if err := rawdb.WriteBlock(bc.db, genesis); err != nil {
+		bc.debug.Errorf("failed to write the genesis block: %v", err)
+	}
+	rawdb.WriteReceipts(bc.db, genesis.Hash(), genesis.Number().Uint64(), receipts)
+	rawdb.WriteTxLookupEntries(bc.db, genesis)
+	rawdb.WriteStateDiff(bc.db, genesis.Hash(), genesis.Number().Uint64(), bc.state.CommitDiff())
+	rawdb.WriteSupplyStats(bc.db, genesis.Hash(), genesis.Number().Uint64(), bc.state.Stats())
+	if mints := bc.state.CommitMints(); len(mints) > 0 {
+		rawdb.WriteBlockMints(bc.db, genesis.Hash(), genesis.Number().Uint64(), mints)
 	}
-	bc.currentBlock.Store(genesis)
+	bc.insert(genesis)
+}
+
+// Genesis returns the genesis the chain was created from
+func (bc *BlockChain) Genesis() *Genesis {
+	return bc.genesis
+}
 
-	return genesis
+// Config returns the rules of the chain
+func (bc *BlockChain) Config() *types.ChainConfig {
+	return bc.config
 }
 
 // GetBlockByHash retrieves a block from the database by hash
@@ -140,22 +251,160 @@ func (bc *BlockChain) GetBlock(hash ibft.Hash, number uint64) *types.Block {
 	return block
 }
 
+// GetReceiptsByHash retrieves the receipts of all the transactions of a block
+func (bc *BlockChain) GetReceiptsByHash(hash ibft.Hash) types.Receipts {
+	number := rawdb.ReadBlockNumber(bc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadReceipts(bc.db, hash, *number)
+}
+
+// GetTransaction retrieves a committed transaction along with the hash and
+// number of its block and its index in the block
+func (bc *BlockChain) GetTransaction(hash ibft.Hash) (*types.Transaction, ibft.Hash, uint64, uint64) {
+	entry := rawdb.ReadTxLookupEntry(bc.db, hash)
+	if entry == nil {
+		return nil, ibft.Hash{}, 0, 0
+	}
+	block := bc.GetBlock(entry.BlockHash, entry.BlockIndex)
+	if block == nil || entry.Index >= uint64(len(block.Transactions)) {
+		return nil, ibft.Hash{}, 0, 0
+	}
+	return block.Transactions[entry.Index], entry.BlockHash, entry.BlockIndex, entry.Index
+}
+
+// GetReceipt retrieves the receipt of a committed transaction
+func (bc *BlockChain) GetReceipt(hash ibft.Hash) *types.Receipt {
+	entry := rawdb.ReadTxLookupEntry(bc.db, hash)
+	if entry == nil {
+		return nil
+	}
+	receipts := rawdb.ReadReceipts(bc.db, entry.BlockHash, entry.BlockIndex)
+	if entry.Index >= uint64(len(receipts)) {
+		return nil
+	}
+	return receipts[entry.Index]
+}
+
+// GetAddressTransactions retrieves the positions of the transactions sent or
+// received by an address, most recent first
+func (bc *BlockChain) GetAddressTransactions(addr ibft.Address, offset int, limit int) []*rawdb.TxLookupEntry {
+	return rawdb.ReadAddressTxEntries(bc.db, addr, offset, limit)
+}
+
+// GetReferenceTransactions retrieves the positions of the payments carrying
+// reference sent or received by an address, most recent first
+func (bc *BlockChain) GetReferenceTransactions(addr ibft.Address, reference []byte, offset int, limit int) []*rawdb.TxLookupEntry {
+	return rawdb.ReadReferenceTxEntries(bc.db, addr, reference, offset, limit)
+}
+
+// GetBlockCharges retrieves the demurrage charged by a block
+func (bc *BlockChain) GetBlockCharges(hash ibft.Hash, number uint64) types.DemurrageCharges {
+	return rawdb.ReadDemurrageCharges(bc.db, hash, number)
+}
+
+// GetAccountCharges retrieves the demurrage charged to an account, most recent
+// first
+func (bc *BlockChain) GetAccountCharges(addr ibft.Address, offset int, limit int) types.DemurrageCharges {
+	return rawdb.ReadAccountCharges(bc.db, addr, offset, limit)
+}
+
 // WriteBlock writes the block to the database
 func (bc *BlockChain) WriteBlock(block *types.Block, receipts []*types.Receipt) error {
-	bc.debug.Infof("WriteBlock (%d, %v) parent: %v", block.Number().Uint64(), block.Hash(), block.ParentHash())
 	// Make sure no inconsistent state is leaked during insertion
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
+	return bc.writeBlock(block, receipts)
+}
 
-	rawdb.WriteBlock(bc.db, block)
+// writeBlock stores a block processed on the current state and makes it the
+// head. Nothing but the block is written when it cannot be stored. Note, this
+// function assumes that the `mu` mutex is held!
+func (bc *BlockChain) writeBlock(block *types.Block, receipts []*types.Receipt) error {
+	bc.debug.Infof("WriteBlock (%d, %v) parent: %v", block.Number().Uint64(), block.Hash(), block.ParentHash())
+	if err := rawdb.WriteBlock(bc.db, block); err != nil {
+		return err
+	}
 	// Write the metadata for transaction/receipt lookups and preimages
 	rawdb.WriteReceipts(bc.db, block.Hash(), block.Number().Uint64(), receipts)
-	// TODO: rawdb.WriteTxLookupEntries(batch, block) # USING A batch !!
+	rawdb.WriteTxLookupEntries(bc.db, block)
+	rawdb.WriteStateDiff(bc.db, block.Hash(), block.Number().Uint64(), bc.state.CommitDiff())
+	if charges := bc.state.CommitCharges(); len(charges) > 0 {
+		rawdb.WriteDemurrageCharges(bc.db, block.Hash(), block.Number().Uint64(), charges)
+	}
+	rawdb.WriteSupplyStats(bc.db, block.Hash(), block.Number().Uint64(), bc.state.Stats())
+	if mints := bc.state.CommitMints(); len(mints) > 0 {
+		rawdb.WriteBlockMints(bc.db, block.Hash(), block.Number().Uint64(), mints)
+	}
+	bc.writeCheckpoint(block.Hash(), block.Number().Uint64())
 
 	bc.insert(block)
+	bc.pruneState(block.Number().Uint64())
 	return nil
 }
 
+// writeCheckpoint stores the current state when the block number is a multiple
+// of the checkpoint interval, so that historical states are rebuilt from at
+// most that many diffs. Note, this function assumes that the `mu` mutex is
+// held!
+func (bc *BlockChain) writeCheckpoint(hash ibft.Hash, number uint64) {
+	if *checkpoints == 0 || number == 0 || number%*checkpoints != 0 {
+		return
+	}
+	rawdb.WriteStateCheckpoint(bc.db, hash, number, &types.StateSnapshot{
+		Number:   new(big.Int).SetUint64(number),
+		Accounts: bc.state.Dump(),
+		Mints:    bc.state.Mints(),
+	})
+}
+
+// pruneState folds the state diffs that fell out of the history window into
+// the stored snapshot. It does nothing on archive nodes.
+func (bc *BlockChain) pruneState(head uint64) {
+	if *stateHistory == 0 || head <= *stateHistory {
+		return
+	}
+	oldest := head - *stateHistory
+
+	snapshot := rawdb.ReadStateSnapshot(bc.db)
+	next := uint64(0)
+	if snapshot != nil {
+		next = snapshot.Number.Uint64() + 1
+	}
+	if next > oldest {
+		return
+	}
+
+	st := state.New(bc.config)
+	if snapshot != nil {
+		st.ApplyDiff(snapshot.Accounts)
+		st.ApplyMints(snapshot.Mints)
+	}
+	for nr := next; nr <= oldest; nr++ {
+		hash := rawdb.ReadBlockHash(bc.db, nr)
+		diff, ok := rawdb.ReadStateDiff(bc.db, hash, nr)
+		if !ok {
+			bc.debug.Errorf("prune state failed on #%d: diff not found", nr)
+			return
+		}
+		st.ApplyDiff(diff)
+		st.ApplyMints(rawdb.ReadBlockMints(bc.db, hash, nr))
+	}
+	rawdb.WriteStateSnapshot(bc.db, &types.StateSnapshot{
+		Number:   new(big.Int).SetUint64(oldest),
+		Accounts: st.Dump(),
+		Mints:    st.Mints(),
+	})
+	for nr := next; nr <= oldest; nr++ {
+		hash := rawdb.ReadBlockHash(bc.db, nr)
+		rawdb.DeleteStateDiff(bc.db, hash, nr)
+		rawdb.DeleteBlockMints(bc.db, hash, nr)
+		rawdb.DeleteStateCheckpoint(bc.db, hash, nr)
+	}
+	bc.debug.Infof("Pruned state history up to #%d", oldest)
+}
+
 // insert injects a new head block into the current block chain. This method
 // assumes that the block is indeed a true head. It will update currenctHead
 // Note, this function assumes that the `mu` mutex is held!
@@ -172,6 +421,17 @@ func (bc *BlockChain) CurrentBlock() *types.Block {
 	return bc.currentBlock.Load().(*types.Block)
 }
 
+// Close closes the database. The blockchain cannot be used afterwards.
+func (bc *BlockChain) Close() error {
+	unregisterDBMetrics(bc.db)
+	return bc.db.Close()
+}
+
+// CheckWritable returns an error if the database does not accept writes
+func (bc *BlockChain) CheckWritable() error {
+	return rawdb.CheckWritable(bc.db)
+}
+
 // HasBlock checks if a block is fully present in the database or not.
 func (bc *BlockChain) HasBlock(hash ibft.Hash, number uint64) bool {
 	return rawdb.HasBlock(bc.db, hash, number)
@@ -204,6 +464,50 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 	return nil
 }
 
+// Import inserts the blocks of an RLP stream, as written by ExportN, into the
+// chain. Blocks are decoded and inserted by batches of importBatchSize so that
+// the whole chain never sits in memory. Blocks already part of the chain are
+// skipped. It returns the number of inserted blocks.
+func (bc *BlockChain) Import(r io.Reader) (int, error) {
+	stream := rlp.NewStream(r, 0)
+	batch := make([]*types.Block, 0, importBatchSize)
+	imported := 0
+
+	insert := func() error {
+		if err := bc.InsertChain(batch); err != nil {
+			return err
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			break
+		} else if err != nil {
+			return imported, fmt.Errorf("import failed after %d blocks: %v", imported, err)
+		}
+
+		number := block.Number().Uint64()
+		if len(batch) == 0 && number <= bc.CurrentBlock().Number().Uint64() {
+			if !bc.HasBlock(block.Hash(), number) {
+				return imported, fmt.Errorf("import failed on #%d: conflicts with the local chain", number)
+			}
+			continue
+		}
+
+		batch = append(batch, block)
+		if len(batch) == importBatchSize {
+			if err := insert(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	return imported, insert()
+}
+
 // EncodeRLP implements encodeRLPer
 func (bc *BlockChain) EncodeRLP(w io.Writer) error {
 	return bc.Export(w)
@@ -217,6 +521,10 @@ func (bc *BlockChain) InsertChain(blockChain []*types.Block) error {
 		return nil
 	}
 
+	// Concurrent imports would process their blocks on the same state
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
 	// Check if the first block is a child of the current head
 	if blockChain[0].ParentHash() != bc.CurrentBlock().Hash() || blockChain[0].Number().Uint64() != bc.CurrentBlock().Number().Uint64()+1 {
 		bc.debug.Error("Non contiguous receipt insert ", "number ", blockChain[0].Number(), " hash ", blockChain[0].Hash(), " parent ", blockChain[0].ParentHash(),
@@ -234,13 +542,22 @@ func (bc *BlockChain) InsertChain(blockChain []*types.Block) error {
 		}
 	}
 
+	// Readers must not see the state of a block before it is written
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	for _, block := range blockChain {
 		receipts, err := bc.state.ProcessBlock(block)
+		if err == nil {
+			// Write all the data out into the database
+			err = bc.writeBlock(block, receipts)
+		}
 		if err != nil {
+			// The state may hold the block, restore the one of the head
+			if st, serr := bc.stateAt(bc.CurrentBlock().Number().Uint64()); serr == nil {
+				bc.state = st
+			}
 			return err
 		}
-		// Write all the data out into the database
-		bc.WriteBlock(block, receipts)
 	}
 	return nil
 }
@@ -250,6 +567,56 @@ func (bc *BlockChain) State() *state.StateDB {
 	return bc.state
 }
 
+// StateAt rebuilds the state as it was at the end of the given block. The
+// returned state is a copy and can be freely modified.
+func (bc *BlockChain) StateAt(number uint64) (*state.StateDB, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if head := bc.CurrentBlock().Number().Uint64(); number > head {
+		return nil, fmt.Errorf("block #%d is ahead of the head #%d", number, head)
+	}
+	return bc.stateAt(number)
+}
+
+// stateAt rebuilds the state at the end of the given canonical block from the
+// closest stored snapshot or checkpoint and the following state diffs. Note,
+// this function assumes that the `mu` mutex is held!
+func (bc *BlockChain) stateAt(number uint64) (*state.StateDB, error) {
+	snapshot := rawdb.ReadStateSnapshot(bc.db)
+	if snapshot != nil && number < snapshot.Number.Uint64() {
+		return nil, errStatePruned
+	}
+	if *checkpoints != 0 {
+		if nr := number - number%*checkpoints; nr > 0 && (snapshot == nil || nr > snapshot.Number.Uint64()) {
+			if checkpoint := rawdb.ReadStateCheckpoint(bc.db, rawdb.ReadBlockHash(bc.db, nr), nr); checkpoint != nil {
+				snapshot = checkpoint
+			}
+		}
+	}
+
+	st := state.New(bc.config)
+	next := uint64(0)
+	if snapshot != nil {
+		st.ApplyDiff(snapshot.Accounts)
+		st.ApplyMints(snapshot.Mints)
+		next = snapshot.Number.Uint64() + 1
+	}
+	for nr := next; nr <= number; nr++ {
+		hash := rawdb.ReadBlockHash(bc.db, nr)
+		diff, ok := rawdb.ReadStateDiff(bc.db, hash, nr)
+		if !ok {
+			return nil, fmt.Errorf("state of block #%d not found", nr)
+		}
+		st.ApplyDiff(diff)
+		st.ApplyMints(rawdb.ReadBlockMints(bc.db, hash, nr))
+	}
+	if stats := rawdb.ReadSupplyStats(bc.db, rawdb.ReadBlockHash(bc.db, number), number); stats != nil {
+		st.SetStats(stats)
+	}
+	return st, nil
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesis(bc.genesisBlock)
@@ -259,43 +626,57 @@ func (bc *BlockChain) Reset() error {
 // specified genesis state.
 func (bc *BlockChain) ResetWithGenesis(genesis *types.Block) error {
 	bc.debug.Infof("ResetWithGenesis (%d, %v)", genesis.Number().Uint64(), genesis.Hash())
-	// Dump the entire block chain and purge the caches
-	if err := bc.SetHead(0); err != nil {
-		return err
-	}
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
-	bc.state = state.New()
-	rawdb.WriteBlock(bc.db, genesis)
-	bc.insert(genesis)
+
+	// Dump the entire block chain and purge the caches
+	bc.rewind(0)
+	rawdb.DeleteStateSnapshot(bc.db)
+	bc.writeGenesisBlock(genesis)
 	bc.debug.Infof("Successful reset to genesis hash %v", bc.CurrentBlock().Hash())
 
 	bc.genesisBlock = genesis
 	return nil
 }
 
-// SetHead rewinds the local chain to a new head. In the case of headers, everything
-// above the new head will be deleted and the new one set.
+// SetHead rewinds the local chain to a new head. Everything above the new head
+// is deleted, along with its receipts, state diffs and transaction indexes,
+// and the state is rebuilt as it was at the new head. Pruning nodes cannot
+// rewind below their oldest kept state.
 func (bc *BlockChain) SetHead(head uint64) error {
 	bc.debug.Infof("setHead(%d)", head)
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
-	block := bc.CurrentBlock()
-	for block.Number().Uint64() > head {
-		bc.debug.Infof("Delete (%d, %v)", block.Number().Uint64(), block.Hash())
-		rawdb.DeleteBlockHash(bc.db, block.Number().Uint64())
-		rawdb.DeleteBlock(bc.db, block.Hash(), block.Number().Uint64())
-		block = bc.GetBlock(block.ParentHash(), block.Number().Uint64()-1)
+	if snapshot := rawdb.ReadStateSnapshot(bc.db); snapshot != nil && head < snapshot.Number.Uint64() {
+		return errStatePruned
 	}
+	bc.rewind(head)
+	st, err := bc.stateAt(bc.CurrentBlock().Number().Uint64())
+	if err != nil {
+		return err
+	}
+	bc.state = st
+	return nil
+}
 
-	bc.currentBlock.Store(block)
-
-	// If either blocks reached nil, reset to the genesis state
-	if currentBlock := bc.CurrentBlock(); currentBlock == nil {
-		bc.currentBlock.Store(bc.genesisBlock)
+// rewind deletes the canonical blocks above head. Note, this function assumes
+// that the `mu` mutex is held!
+func (bc *BlockChain) rewind(head uint64) {
+	block := bc.CurrentBlock()
+	for block != nil && block.Number().Uint64() > head {
+		number := block.Number().Uint64()
+		bc.debug.Infof("Delete (%d, %v)", number, block.Hash())
+		rawdb.DeleteBlockHash(bc.db, number)
+		rawdb.DeleteTxLookupEntries(bc.db, block)
+		rawdb.DeleteBlock(bc.db, block.Hash(), number)
+		block = bc.GetBlock(block.ParentHash(), number-1)
 	}
 
-	rawdb.WriteHeadBlockHash(bc.db, bc.CurrentBlock().Hash())
-	return nil
+	// If the parent of a deleted block is missing, reset to the genesis block
+	if block == nil {
+		block = bc.genesisBlock
+	}
+	bc.currentBlock.Store(block)
+	rawdb.WriteHeadBlockHash(bc.db, block.Hash())
 }
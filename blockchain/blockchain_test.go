@@ -0,0 +1,380 @@
+package blockchain_test
+
+import (
+	"crypto/ecdsa"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/rawdb"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	alice = ibft.Address{1}
+	bob   = ibft.Address{2}
+
+	issuerKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+)
+
+func keyAddress(key *ecdsa.PrivateKey) ibft.Address {
+	addr := ibft.Address{}
+	addr.FromBytes(crypto.PubkeyToAddress(key.PublicKey).Bytes())
+	return addr
+}
+
+// mint returns a mint approved by the issuers of keys
+func mint(to ibft.Address, amount int64, keys ...*ecdsa.PrivateKey) *types.Transaction {
+	tx := types.NewMint(to, big.NewInt(amount))
+	for _, key := range keys {
+		var err error
+		if tx, err = types.AddMintSignature(tx, key); err != nil {
+			panic(err)
+		}
+	}
+	return tx
+}
+
+// newTestChain returns a chain of the main network rules, issued by issuerKey
+func newTestChain() (*blockchain.BlockChain, func()) {
+	genesis := blockchain.DefaultGenesis()
+	genesis.Config.Issuance.Issuers = []ibft.Address{keyAddress(issuerKey)}
+	return newTestChainWithGenesis(genesis)
+}
+
+func newTestChainWithGenesis(genesis *blockchain.Genesis) (*blockchain.BlockChain, func()) {
+	dir, err := ioutil.TempDir("", "blockchain")
+	if err != nil {
+		panic(err)
+	}
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		panic(err)
+	}
+	return bc, func() {
+		bc.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// insertBlocks appends a block holding each transaction to the chain
+func insertBlocks(bc *blockchain.BlockChain, txs ...*types.Transaction) {
+	for _, tx := range txs {
+		parent := bc.CurrentBlock()
+		block := types.NewBlock(&types.Header{
+			Number:     new(big.Int).Add(parent.Number(), big.NewInt(1)),
+			ParentHash: parent.Hash(),
+			Time:       new(big.Int).Add(parent.Header.Time, big.NewInt(1)),
+		}, types.Transactions{tx})
+		if err := bc.InsertChain([]*types.Block{block}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestSetHeadRestoresState(t *testing.T) {
+	bc, cleanup := newTestChain()
+	defer cleanup()
+
+	insertBlocks(bc,
+		mint(alice, 100, issuerKey),
+		types.NewTransaction(alice, bob, big.NewInt(30)),
+		types.NewTransaction(alice, bob, big.NewInt(20)),
+	)
+	removed := bc.GetBlockByNumber(3)
+
+	if err := bc.SetHead(2); err != nil {
+		t.Fatal(err)
+	}
+	if head := bc.CurrentBlock().Number().Uint64(); head != 2 {
+		t.Fatalf("got head #%d, want #2", head)
+	}
+	if balance := bc.State().GetBalance(alice); balance.Int64() != 70 {
+		t.Fatalf("got balance %v for alice after rewind, want 70", balance)
+	}
+	if supply := bc.State().TotalSupply(); supply.Int64() != 100 {
+		t.Fatalf("got supply %v after rewind, want 100", supply)
+	}
+	if bc.GetBlockByNumber(3) != nil || bc.GetReceiptsByHash(removed.Hash()) != nil {
+		t.Fatal("rewound block is still stored")
+	}
+	if tx, _, _, _ := bc.GetTransaction(removed.Transactions[0].Hash()); tx != nil {
+		t.Fatal("transaction of a rewound block is still indexed")
+	}
+	if problems := bc.CheckIntegrity(); len(problems) != 0 {
+		t.Fatalf("integrity problems after rewind: %v", problems)
+	}
+
+	// Blocks inserted on the rewound head apply to the restored state
+	insertBlocks(bc, types.NewTransaction(alice, bob, big.NewInt(70)))
+	if balance := bc.State().GetBalance(bob); balance.Int64() != 100 {
+		t.Fatalf("got balance %v for bob, want 100", balance)
+	}
+}
+
+func TestBatchTransfer(t *testing.T) {
+	bc, cleanup := newTestChain()
+	defer cleanup()
+	carol := ibft.Address{3}
+
+	insertBlocks(bc,
+		mint(alice, 100, issuerKey),
+		types.NewBatch(alice, []*types.TxOutput{{To: bob, Amount: big.NewInt(30)}, {To: carol, Amount: big.NewInt(50)}}),
+		// bob cannot pay both outputs, so neither is paid
+		types.NewBatch(bob, []*types.TxOutput{{To: alice, Amount: big.NewInt(20)}, {To: carol, Amount: big.NewInt(20)}}),
+	)
+	st := bc.State()
+	for addr, want := range map[ibft.Address]int64{alice: 20, bob: 30, carol: 50} {
+		if balance := st.GetBalance(addr); balance.Int64() != want {
+			t.Errorf("got balance %v for %x, want %d", balance, addr, want)
+		}
+	}
+
+	paid := bc.GetReceiptsByHash(bc.GetBlockByNumber(2).Hash())[0]
+	if paid.Status != types.ReceiptStatusSuccessful || len(paid.Logs) != 2 || paid.Logs[1].Kind != types.LogOutput || paid.Logs[1].Account != carol || paid.Logs[1].Amount.Int64() != 50 {
+		t.Errorf("got receipt %+v for the paid batch", paid)
+	}
+	if failed := bc.GetReceiptsByHash(bc.CurrentBlock().Hash())[0]; failed.Status != types.ReceiptStatusFailed || len(failed.Logs) != 0 {
+		t.Errorf("got receipt %+v for the unpaid batch", failed)
+	}
+	// Every recipient of a batch finds it in its transactions
+	if entries := bc.GetAddressTransactions(carol, 0, 10); len(entries) != 2 {
+		t.Errorf("got %d transactions for carol, want 2", len(entries))
+	}
+}
+
+func TestZeroAmountTransfer(t *testing.T) {
+	bc, cleanup := newTestChain()
+	defer cleanup()
+
+	insertBlocks(bc,
+		mint(alice, 100, issuerKey),
+		types.NewTransaction(alice, bob, big.NewInt(0)),
+		types.NewPayment(alice, bob, big.NewInt(0), []byte("INV-1")),
+		types.NewBatch(alice, []*types.TxOutput{{To: bob, Amount: big.NewInt(10)}, {To: bob, Amount: big.NewInt(0)}}),
+	)
+	for n := uint64(2); n <= 4; n++ {
+		if receipt := bc.GetReceiptsByHash(bc.GetBlockByNumber(n).Hash())[0]; receipt.Status != types.ReceiptStatusFailed {
+			t.Errorf("zero amount transaction of block #%d succeeded", n)
+		}
+	}
+	if balance := bc.State().GetBalance(alice); balance.Int64() != 100 {
+		t.Errorf("got balance %v for alice, want 100", balance)
+	}
+	if accounts := bc.State().Stats().Accounts; accounts != 1 {
+		t.Errorf("got %d accounts, want 1", accounts)
+	}
+}
+
+func TestSupplyInvariant(t *testing.T) {
+	carol := ibft.Address{3}
+	for _, destination := range []string{types.DemurrageBurn, types.DemurrageTreasury, types.DemurrageRedistribute} {
+		genesis := blockchain.DefaultGenesis()
+		genesis.Config.Issuance.Issuers = []ibft.Address{keyAddress(issuerKey)}
+		genesis.Config.Demurrage = types.DemurrageConfig{Interval: 2, Rate: 7, Destination: destination, Treasury: carol}
+		genesis.Alloc = map[ibft.Address]*big.Int{alice: big.NewInt(1000)}
+		bc, cleanup := newTestChainWithGenesis(genesis)
+		defer cleanup()
+
+		insertBlocks(bc,
+			mint(bob, 333, issuerKey),
+			types.NewTransaction(alice, carol, big.NewInt(101)),
+			types.NewTransaction(bob, alice, big.NewInt(5000)),
+			types.NewBurn(alice, big.NewInt(77)),
+			mint(carol, 10),
+			types.NewTransaction(bob, alice, bc.State().GetBalance(bob)),
+			mint(ibft.Address{4}, 1, issuerKey),
+		)
+		for nr := uint64(0); nr <= bc.CurrentBlock().Number().Uint64(); nr++ {
+			st, err := bc.StateAt(nr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := blockchain.CheckSupply(st); err != nil {
+				t.Errorf("%s: block #%d: %v", destination, nr, err)
+			}
+		}
+		if stats := bc.State().Stats(); stats.Minted.Int64() != 1334 || stats.Burned.Int64() != 77 || stats.DemurrageBurned.Sign() == 0 && destination == types.DemurrageBurn {
+			t.Errorf("%s: unexpected stats %+v", destination, stats)
+		}
+
+		if err := bc.SetHead(3); err != nil {
+			t.Fatal(err)
+		}
+		if problems := bc.CheckIntegrity(); len(problems) != 0 {
+			t.Errorf("%s: integrity problems after rewind: %v", destination, problems)
+		}
+		if holders := bc.State().TopHolders(1); len(holders) != 1 || holders[0].Address != alice {
+			t.Errorf("%s: got top holders %v", destination, holders)
+		}
+	}
+}
+
+func TestStateAt(t *testing.T) {
+	for name, value := range map[string]string{"state-checkpoints": "4", "state-history": "0"} {
+		previous := flag.Lookup(name).Value.String()
+		flag.Set(name, value)
+		defer flag.Set(name, previous)
+	}
+	bc, cleanup := newTestChain()
+	defer cleanup()
+
+	// Balances of alice and bob at the end of each block
+	want := [][2]int64{{0, 0}}
+	insertBlocks(bc, mint(alice, 100, issuerKey))
+	want = append(want, [2]int64{100, 0})
+	for i := int64(1); i <= 10; i++ {
+		insertBlocks(bc, types.NewTransaction(alice, bob, big.NewInt(i)))
+		last := want[len(want)-1]
+		want = append(want, [2]int64{last[0] - i, last[1] + i})
+	}
+	check := func(from uint64) {
+		for nr := from; nr < uint64(len(want)); nr++ {
+			st, err := bc.StateAt(nr)
+			if err != nil {
+				t.Fatalf("block #%d: %v", nr, err)
+			}
+			if a, b := st.GetBalance(alice).Int64(), st.GetBalance(bob).Int64(); a != want[nr][0] || b != want[nr][1] {
+				t.Errorf("block #%d: got balances %d and %d, want %v", nr, a, b, want[nr])
+			}
+			if supply := st.TotalSupply().Int64(); nr > 0 && supply != 100 {
+				t.Errorf("block #%d: got supply %d, want 100", nr, supply)
+			}
+		}
+	}
+	// States before, on and after the checkpoints of blocks #4 and #8
+	check(0)
+	if _, err := bc.StateAt(uint64(len(want))); err == nil {
+		t.Error("got the state of a block above the head")
+	}
+
+	// Once pruned, older states are refused and newer ones are still rebuilt
+	flag.Set("state-history", "3")
+	insertBlocks(bc, types.NewTransaction(bob, alice, big.NewInt(5)))
+	last := want[len(want)-1]
+	want = append(want, [2]int64{last[0] + 5, last[1] - 5})
+	pruned := bc.CurrentBlock().Number().Uint64() - 3
+	if _, err := bc.StateAt(pruned - 1); err == nil {
+		t.Errorf("got the pruned state of block #%d", pruned-1)
+	}
+	check(pruned)
+	if problems := bc.CheckIntegrity(); len(problems) != 0 {
+		t.Errorf("integrity problems after pruning: %v", problems)
+	}
+}
+
+func TestPruneSurvivesRestart(t *testing.T) {
+	for name, value := range map[string]string{"state-checkpoints": "2", "state-history": "3"} {
+		previous := flag.Lookup(name).Value.String()
+		flag.Set(name, value)
+		defer flag.Set(name, previous)
+	}
+	dir, err := ioutil.TempDir("", "blockchain")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	genesis := blockchain.DefaultGenesis()
+	genesis.Config.Issuance.Issuers = []ibft.Address{keyAddress(issuerKey)}
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertBlocks(bc, mint(alice, 100, issuerKey))
+	for i := 0; i < 7; i++ {
+		insertBlocks(bc, types.NewTransaction(alice, bob, big.NewInt(1)))
+	}
+	pruned := bc.CurrentBlock().Number().Uint64() - 3
+	bc.Close()
+
+	// Restarts replay the whole chain without writing back the pruned history
+	for i := 0; i < 2; i++ {
+		if bc, err = blockchain.NewWithGenesis(dir, nil); err != nil {
+			t.Fatal(err)
+		}
+		if balance := bc.State().GetBalance(bob); balance.Int64() != 7 {
+			t.Errorf("got balance %v for bob after a restart", balance)
+		}
+		bc.Close()
+	}
+	db, err := rawdb.InitDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for nr := uint64(0); nr <= pruned; nr++ {
+		hash := rawdb.ReadBlockHash(db, nr)
+		if rawdb.HasStateDiff(db, hash, nr) || rawdb.HasStateCheckpoint(db, hash, nr) || len(rawdb.ReadBlockMints(db, hash, nr)) > 0 {
+			t.Errorf("the pruned state history of block #%d was written back", nr)
+		}
+	}
+	if hash := rawdb.ReadBlockHash(db, pruned+1); !rawdb.HasStateDiff(db, hash, pruned+1) {
+		t.Errorf("the state diff of block #%d is missing", pruned+1)
+	}
+}
+
+func TestInsertChainConcurrentSetHead(t *testing.T) {
+	bc, cleanup := newTestChain()
+	defer cleanup()
+	insertBlocks(bc, mint(alice, 10000, issuerKey))
+
+	// Rewinds replace the state while blocks are processed on it. Inserts
+	// may be refused when their parent was just rewound.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			parent := bc.CurrentBlock()
+			bc.InsertChain([]*types.Block{types.NewBlock(&types.Header{
+				Number:     new(big.Int).Add(parent.Number(), big.NewInt(1)),
+				ParentHash: parent.Hash(),
+				Time:       new(big.Int).Add(parent.Header.Time, big.NewInt(1)),
+			}, types.Transactions{types.NewTransaction(alice, bob, big.NewInt(int64(i+1)))})})
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if err := bc.SetHead(bc.CurrentBlock().Number().Uint64()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paid := int64(0)
+	for nr := uint64(2); nr <= bc.CurrentBlock().Number().Uint64(); nr++ {
+		paid += bc.GetBlockByNumber(nr).Transactions[0].Amount.Int64()
+	}
+	if balance := bc.State().GetBalance(bob); balance.Int64() != paid {
+		t.Fatalf("got balance %v for bob, want %d", balance, paid)
+	}
+	if problems := bc.CheckIntegrity(); len(problems) != 0 {
+		t.Fatalf("integrity problems: %v", problems)
+	}
+}
+
+func TestInsertChainWriteError(t *testing.T) {
+	bc, cleanup := newTestChain()
+	defer cleanup()
+	head := bc.CurrentBlock()
+	bc.Close()
+
+	block := types.NewBlock(&types.Header{
+		Number:     big.NewInt(1),
+		ParentHash: head.Hash(),
+		Time:       big.NewInt(1),
+	}, types.Transactions{mint(alice, 100, issuerKey)})
+	if err := bc.InsertChain([]*types.Block{block}); err == nil {
+		t.Fatal("inserted a block into a closed database")
+	}
+	if bc.CurrentBlock().Hash() != head.Hash() {
+		t.Fatalf("head moved to #%d after a failed write", bc.CurrentBlock().Number())
+	}
+}
@@ -0,0 +1,361 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strings"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+var (
+	genesisPath = flag.String("genesis", "", "genesis configuration file of a new chain (default: the main network)")
+
+	errGenesisMismatch = errors.New("the chain was created from another genesis")
+)
+
+// mainnetGenesis is the genesis of the main network
+const mainnetGenesis = `{
+  "config": {
+    "chainId": 1,
+    "issuance": {"issuers": ["0dedc69acd6405e2459c93efb0dbfa8d9c0b5ccb"], "threshold": 1, "schedule": []},
+    "blockInterval": 20,
+    "demurrage": {"interval": 4320, "rate": 3000, "exempt": [], "destination": "burn"}
+  },
+  "timestamp": 1546300800,
+  "alloc": {},
+  "validators": [],
+  "extraData": ""
+}`
+
+// Genesis defines the first block of a chain and the rules of its network
+type Genesis struct {
+	Config    *types.ChainConfig
+	Timestamp uint64
+	// Alloc lists the initial balances, credited by mints in the genesis block
+	Alloc map[ibft.Address]*big.Int
+	// Validators lists the validators allowed to join the network. Anyone can
+	// join when it is empty.
+	Validators []ibft.Address
+	ExtraData  []byte
+}
+
+// genesisJSON is the JSON encoding of a genesis. Addresses and extra data are
+// hex encoded.
+type genesisJSON struct {
+	Config struct {
+		ChainID  *big.Int `json:"chainId"`
+		Issuance struct {
+			Issuers     []string        `json:"issuers"`
+			Threshold   uint64          `json:"threshold"`
+			Schedule    []supplyCapJSON `json:"schedule"`
+			LegacyRoot  string          `json:"legacyRoot,omitempty"`
+			LegacyBlock uint64          `json:"legacyBlock,omitempty"`
+		} `json:"issuance"`
+		BlockInterval uint64 `json:"blockInterval"`
+		Demurrage     struct {
+			Interval    uint64   `json:"interval"`
+			Rate        uint64   `json:"rate"`
+			Exempt      []string `json:"exempt"`
+			Destination string   `json:"destination"`
+			Treasury    string   `json:"treasury,omitempty"`
+		} `json:"demurrage"`
+	} `json:"config"`
+	Timestamp  uint64              `json:"timestamp"`
+	Alloc      map[string]*big.Int `json:"alloc"`
+	Validators []string            `json:"validators"`
+	ExtraData  string              `json:"extraData"`
+}
+
+type supplyCapJSON struct {
+	Block uint64   `json:"block"`
+	Cap   *big.Int `json:"cap"`
+}
+
+// DefaultGenesis returns the genesis of the main network
+func DefaultGenesis() *Genesis {
+	g := &Genesis{}
+	if err := json.Unmarshal([]byte(mainnetGenesis), g); err != nil {
+		panic("invalid main network genesis: " + err.Error())
+	}
+	return g
+}
+
+// LoadGenesis reads a JSON genesis file
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g := &Genesis{}
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, fmt.Errorf("invalid genesis %s: %v", path, err)
+	}
+	return g, nil
+}
+
+// configuredGenesis returns the genesis of the -genesis file, or the one of
+// the main network
+func configuredGenesis() (*Genesis, error) {
+	if *genesisPath == "" {
+		return DefaultGenesis(), nil
+	}
+	return LoadGenesis(*genesisPath)
+}
+
+// GenesisConfigured reports whether the genesis was chosen with -genesis
+func GenesisConfigured() bool {
+	return *genesisPath != ""
+}
+
+func parseAddress(s string) (ibft.Address, error) {
+	bytes, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(bytes) != len(ibft.Address{}) {
+		return ibft.Address{}, fmt.Errorf("invalid address %q", s)
+	}
+	addr := ibft.Address{}
+	addr.FromBytes(bytes)
+	return addr, nil
+}
+
+// UnmarshalJSON decodes and validates a JSON genesis
+func (g *Genesis) UnmarshalJSON(data []byte) error {
+	dec := genesisJSON{}
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	if dec.Config.ChainID == nil || dec.Config.ChainID.Sign() <= 0 {
+		return errors.New("chainId must be positive")
+	}
+	if dec.Config.BlockInterval == 0 {
+		return errors.New("blockInterval must be positive")
+	}
+	issuance, err := decodeIssuance(&dec)
+	if err != nil {
+		return fmt.Errorf("issuance: %v", err)
+	}
+	demurrage, err := decodeDemurrage(dec.Config.Demurrage.Interval, dec.Config.Demurrage.Rate,
+		dec.Config.Demurrage.Exempt, dec.Config.Demurrage.Destination, dec.Config.Demurrage.Treasury)
+	if err != nil {
+		return fmt.Errorf("demurrage: %v", err)
+	}
+
+	alloc := make(map[ibft.Address]*big.Int, len(dec.Alloc))
+	for s, balance := range dec.Alloc {
+		addr, err := parseAddress(s)
+		if err != nil {
+			return fmt.Errorf("alloc: %v", err)
+		}
+		if balance == nil || balance.Sign() < 0 {
+			return fmt.Errorf("alloc: invalid balance for %s", s)
+		}
+		alloc[addr] = balance
+	}
+	validators := make([]ibft.Address, 0, len(dec.Validators))
+	for _, s := range dec.Validators {
+		addr, err := parseAddress(s)
+		if err != nil {
+			return fmt.Errorf("validators: %v", err)
+		}
+		validators = append(validators, addr)
+	}
+	extra, err := hex.DecodeString(strings.TrimPrefix(dec.ExtraData, "0x"))
+	if err != nil {
+		return fmt.Errorf("extraData: %v", err)
+	}
+
+	*g = Genesis{
+		Config: &types.ChainConfig{
+			ChainID:       dec.Config.ChainID,
+			Issuance:      *issuance,
+			BlockInterval: dec.Config.BlockInterval,
+			Demurrage:     *demurrage,
+		},
+		Timestamp:  dec.Timestamp,
+		Alloc:      alloc,
+		Validators: validators,
+		ExtraData:  extra,
+	}
+	return nil
+}
+
+// decodeIssuance validates the issuers and the supply cap schedule of a
+// genesis. The allocations must fit under the cap of the genesis block.
+func decodeIssuance(dec *genesisJSON) (*types.IssuanceConfig, error) {
+	config := &types.IssuanceConfig{
+		Issuers:   make([]ibft.Address, 0, len(dec.Config.Issuance.Issuers)),
+		Threshold: dec.Config.Issuance.Threshold,
+		Schedule:  make([]*types.SupplyCap, 0, len(dec.Config.Issuance.Schedule)),
+	}
+	for _, s := range dec.Config.Issuance.Issuers {
+		addr, err := parseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("issuers: %v", err)
+		}
+		config.Issuers = append(config.Issuers, addr)
+	}
+	if len(config.Issuers) > 0 && (config.Threshold == 0 || config.Threshold > uint64(len(config.Issuers))) {
+		return nil, fmt.Errorf("threshold must be between 1 and the %d issuers", len(config.Issuers))
+	}
+	for i, entry := range dec.Config.Issuance.Schedule {
+		if entry.Cap == nil || entry.Cap.Sign() < 0 {
+			return nil, fmt.Errorf("schedule: invalid cap at block %d", entry.Block)
+		}
+		if i > 0 && entry.Block <= config.Schedule[i-1].Block {
+			return nil, errors.New("schedule: blocks must be increasing")
+		}
+		config.Schedule = append(config.Schedule, &types.SupplyCap{Block: entry.Block, Cap: entry.Cap})
+	}
+
+	if dec.Config.Issuance.LegacyBlock != 0 {
+		addr, err := parseAddress(dec.Config.Issuance.LegacyRoot)
+		if err != nil {
+			return nil, fmt.Errorf("legacyRoot: %v", err)
+		}
+		config.LegacyRoot = addr
+		config.LegacyBlock = dec.Config.Issuance.LegacyBlock
+	} else if dec.Config.Issuance.LegacyRoot != "" {
+		return nil, errors.New("legacyRoot needs a legacyBlock")
+	}
+
+	total := new(big.Int)
+	for _, balance := range dec.Alloc {
+		if balance != nil {
+			total.Add(total, balance)
+		}
+	}
+	if cap := config.CapAt(0); cap != nil && total.Cmp(cap) > 0 {
+		return nil, fmt.Errorf("allocations of %v exceed the supply cap %v", total, cap)
+	}
+	return config, nil
+}
+
+// decodeDemurrage validates the monetary policy of a genesis. The destination
+// defaults to burning the collected amount.
+func decodeDemurrage(interval, rate uint64, exempt []string, destination, treasury string) (*types.DemurrageConfig, error) {
+	if interval != 0 && rate == 0 {
+		return nil, errors.New("rate must be positive")
+	}
+	config := &types.DemurrageConfig{
+		Interval:    interval,
+		Rate:        rate,
+		Exempt:      make([]ibft.Address, 0, len(exempt)),
+		Destination: destination,
+	}
+	for _, s := range exempt {
+		addr, err := parseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("exempt: %v", err)
+		}
+		config.Exempt = append(config.Exempt, addr)
+	}
+	switch destination {
+	case "":
+		config.Destination = types.DemurrageBurn
+	case types.DemurrageBurn, types.DemurrageRedistribute:
+	case types.DemurrageTreasury:
+		addr, err := parseAddress(treasury)
+		if err != nil {
+			return nil, fmt.Errorf("treasury: %v", err)
+		}
+		config.Treasury = addr
+	default:
+		return nil, fmt.Errorf("unknown destination %q", destination)
+	}
+	if destination != types.DemurrageTreasury && treasury != "" {
+		return nil, errors.New("treasury is only used by the treasury destination")
+	}
+	return config, nil
+}
+
+// MarshalJSON encodes a genesis in the format read by LoadGenesis
+func (g *Genesis) MarshalJSON() ([]byte, error) {
+	enc := genesisJSON{
+		Timestamp:  g.Timestamp,
+		Alloc:      make(map[string]*big.Int, len(g.Alloc)),
+		Validators: make([]string, 0, len(g.Validators)),
+		ExtraData:  hex.EncodeToString(g.ExtraData),
+	}
+	enc.Config.ChainID = g.Config.ChainID
+	enc.Config.Issuance.Issuers = make([]string, 0, len(g.Config.Issuance.Issuers))
+	for _, addr := range g.Config.Issuance.Issuers {
+		enc.Config.Issuance.Issuers = append(enc.Config.Issuance.Issuers, hex.EncodeToString(addr.Bytes()))
+	}
+	enc.Config.Issuance.Threshold = g.Config.Issuance.Threshold
+	enc.Config.Issuance.Schedule = make([]supplyCapJSON, 0, len(g.Config.Issuance.Schedule))
+	for _, entry := range g.Config.Issuance.Schedule {
+		enc.Config.Issuance.Schedule = append(enc.Config.Issuance.Schedule, supplyCapJSON{entry.Block, entry.Cap})
+	}
+	if g.Config.Issuance.LegacyBlock != 0 {
+		enc.Config.Issuance.LegacyRoot = hex.EncodeToString(g.Config.Issuance.LegacyRoot.Bytes())
+		enc.Config.Issuance.LegacyBlock = g.Config.Issuance.LegacyBlock
+	}
+	enc.Config.BlockInterval = g.Config.BlockInterval
+	enc.Config.Demurrage.Interval = g.Config.Demurrage.Interval
+	enc.Config.Demurrage.Rate = g.Config.Demurrage.Rate
+	enc.Config.Demurrage.Exempt = make([]string, 0, len(g.Config.Demurrage.Exempt))
+	for _, addr := range g.Config.Demurrage.Exempt {
+		enc.Config.Demurrage.Exempt = append(enc.Config.Demurrage.Exempt, hex.EncodeToString(addr.Bytes()))
+	}
+	enc.Config.Demurrage.Destination = g.Config.Demurrage.Destination
+	if g.Config.Demurrage.Destination == types.DemurrageTreasury {
+		enc.Config.Demurrage.Treasury = hex.EncodeToString(g.Config.Demurrage.Treasury.Bytes())
+	}
+	for addr, balance := range g.Alloc {
+		enc.Alloc[hex.EncodeToString(addr.Bytes())] = balance
+	}
+	for _, addr := range g.Validators {
+		enc.Validators = append(enc.Validators, hex.EncodeToString(addr.Bytes()))
+	}
+	return json.Marshal(&enc)
+}
+
+// ToBlock returns the genesis block. Its parent hash commits to the rules of
+// the network, so that networks with different rules have different genesis
+// blocks. The allocations are mints, sorted by address.
+func (g *Genesis) ToBlock() *types.Block {
+	rules := ibft.RlpHash([]interface{}{
+		g.Config,
+		g.Validators,
+		g.ExtraData,
+	})
+
+	addrs := make([]ibft.Address, 0, len(g.Alloc))
+	for addr := range g.Alloc {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].Bytes(), addrs[j].Bytes()) < 0
+	})
+	txs := make(types.Transactions, 0, len(addrs))
+	for _, addr := range addrs {
+		txs = append(txs, types.NewMint(addr, g.Alloc[addr]))
+	}
+
+	return types.NewBlock(&types.Header{
+		Number:     big.NewInt(0),
+		ParentHash: rules,
+		Time:       new(big.Int).SetUint64(g.Timestamp),
+	}, txs)
+}
+
+// IsValidator reports whether addr may join the validators of the network
+func (g *Genesis) IsValidator(addr ibft.Address) bool {
+	if len(g.Validators) == 0 {
+		return true
+	}
+	for _, validator := range g.Validators {
+		if validator == addr {
+			return true
+		}
+	}
+	return false
+}
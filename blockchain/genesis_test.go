@@ -0,0 +1,376 @@
+package blockchain_test
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testGenesis = `{
+  "config": {
+    "chainId": 42,
+    "issuance": {"issuers": ["0x00000000000000000000000000000000000000ff"], "threshold": 1, "schedule": [{"block": 0, "cap": 2000}]},
+    "blockInterval": 5,
+    "demurrage": {"interval": 2, "rate": 10}
+  },
+  "timestamp": 1700000000,
+  "alloc": {
+    "0100000000000000000000000000000000000000": 1000,
+    "0200000000000000000000000000000000000000": 500
+  },
+  "validators": ["0300000000000000000000000000000000000000"],
+  "extraData": "0x74657374"
+}`
+
+func decodeGenesis(s string) *blockchain.Genesis {
+	g := &blockchain.Genesis{}
+	if err := json.Unmarshal([]byte(s), g); err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func TestGenesisChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	genesis := decodeGenesis(testGenesis)
+
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bc.CurrentBlock().Hash() != genesis.ToBlock().Hash() || bc.CurrentBlock().Header.Time.Uint64() != 1700000000 {
+		t.Fatal("chain does not start from the genesis block")
+	}
+	if balance := bc.State().GetBalance(alice); balance.Int64() != 1000 {
+		t.Fatalf("got allocation %v for alice, want 1000", balance)
+	}
+	if types.ChainID.Int64() != 42 || bc.Config().BlockInterval != 5 {
+		t.Fatalf("rules of the genesis not applied: %+v", bc.Config())
+	}
+	if !bc.Genesis().IsValidator(ibft.Address{3}) || bc.Genesis().IsValidator(alice) {
+		t.Fatal("validators of the genesis not applied")
+	}
+
+	// Demurrage takes 1/10 of the balances every 2 blocks, after the
+	// transfers of the block: (1000 - 100 + 10) - 91
+	insertBlocks(bc,
+		types.NewTransaction(alice, bob, big.NewInt(100)),
+		types.NewTransaction(bob, alice, big.NewInt(10)),
+	)
+	if balance := bc.State().GetBalance(alice); balance.Int64() != 819 {
+		t.Fatalf("got balance %v for alice, want 819", balance)
+	}
+	bc.Close()
+
+	// The stored genesis is used when none is given, and another one is
+	// refused
+	if bc, err = blockchain.NewWithGenesis(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	if bc.Config().Demurrage.Rate != 10 || bc.State().GetBalance(alice).Int64() != 819 {
+		t.Fatal("stored genesis not used when reopening the chain")
+	}
+	bc.Close()
+	other := decodeGenesis(strings.Replace(testGenesis, `"chainId": 42`, `"chainId": 43`, 1))
+	if other.ToBlock().Hash() == genesis.ToBlock().Hash() {
+		t.Fatal("genesis blocks of different networks are equal")
+	}
+	if _, err := blockchain.NewWithGenesis(dir, other); err == nil {
+		t.Fatal("opened a chain with another genesis")
+	}
+}
+
+func TestInvalidGenesis(t *testing.T) {
+	for _, replace := range [][2]string{
+		{`"chainId": 42`, `"chainId": 0`},
+		{`"blockInterval": 5`, `"blockInterval": 0`},
+		{`"rate": 10`, `"rate": 0`},
+		{`"0x00000000000000000000000000000000000000ff"`, `"0xff"`},
+		{`1000`, `-1`},
+		{`"rate": 10}`, `"rate": 10, "destination": "treasury"}`},
+		{`"rate": 10}`, `"rate": 10, "destination": "melt"}`},
+		{`"threshold": 1`, `"threshold": 2`},
+		{`"cap": 2000`, `"cap": 1000`},
+		{`"threshold": 1`, `"threshold": 1, "legacyRoot": "0xff", "legacyBlock": 10`},
+		{`"threshold": 1`, `"threshold": 1, "legacyRoot": "0x00000000000000000000000000000000000000ff"`},
+	} {
+		g := &blockchain.Genesis{}
+		if err := json.Unmarshal([]byte(strings.Replace(testGenesis, replace[0], replace[1], 1)), g); err == nil {
+			t.Errorf("accepted genesis with %s", replace[1])
+		}
+	}
+}
+
+func TestDemurrageDestinations(t *testing.T) {
+	for _, test := range []struct {
+		demurrage        string
+		alice, bob, dest int64
+	}{
+		{`{"interval": 2, "rate": 10, "exempt": ["0200000000000000000000000000000000000000"]}`, 900, 500, 0},
+		{`{"interval": 2, "rate": 10, "destination": "treasury", "treasury": "0400000000000000000000000000000000000000"}`, 900, 450, 150},
+		{`{"interval": 2, "rate": 10, "destination": "redistribute"}`, 975, 525, 0},
+	} {
+		dir, err := ioutil.TempDir("", "genesis")
+		if err != nil {
+			panic(err)
+		}
+		defer os.RemoveAll(dir)
+		genesis := decodeGenesis(strings.Replace(testGenesis, `{"interval": 2, "rate": 10}`, test.demurrage, 1))
+		bc, err := blockchain.NewWithGenesis(dir, genesis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer bc.Close()
+
+		insertBlocks(bc,
+			types.NewTransaction(alice, bob, big.NewInt(0)),
+			types.NewTransaction(alice, bob, big.NewInt(0)),
+		)
+		st := bc.State()
+		if st.GetBalance(alice).Int64() != test.alice || st.GetBalance(bob).Int64() != test.bob || st.GetBalance(ibft.Address{4}).Int64() != test.dest {
+			t.Errorf("%s: got balances %v %v %v", test.demurrage, st.GetBalance(alice), st.GetBalance(bob), st.GetBalance(ibft.Address{4}))
+		}
+		charges := bc.GetAccountCharges(alice, 0, 10)
+		if len(charges) != 1 || charges[0].Block != 2 || charges[0].Amount.Int64() != 100 {
+			t.Errorf("%s: got charges %+v for alice", test.demurrage, charges)
+		}
+
+		if err := bc.SetHead(1); err != nil {
+			t.Fatal(err)
+		}
+		if charges := bc.GetAccountCharges(alice, 0, 10); len(charges) != 0 {
+			t.Errorf("%s: charges of a rewound block kept", test.demurrage)
+		}
+	}
+}
+
+func TestMintApproval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	keys := make([]*ecdsa.PrivateKey, 3)
+	issuers := make([]string, 3)
+	for i := range keys {
+		if keys[i], err = crypto.GenerateKey(); err != nil {
+			panic(err)
+		}
+		issuers[i] = `"` + hex.EncodeToString(keyAddress(keys[i]).Bytes()) + `"`
+	}
+	genesis := decodeGenesis(strings.NewReplacer(
+		`"issuers": ["0x00000000000000000000000000000000000000ff"], "threshold": 1`, `"issuers": [`+strings.Join(issuers, ",")+`], "threshold": 2`,
+		`"cap": 2000}`, `"cap": 2000}, {"block": 3, "cap": 1700}`,
+		`"interval": 2`, `"interval": 0`,
+	).Replace(testGenesis))
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	if supply := bc.State().TotalSupply(); supply.Int64() != 1500 {
+		t.Fatalf("got genesis supply %v, want 1500", supply)
+	}
+
+	for _, test := range []struct {
+		tx     *types.Transaction
+		status uint64
+		supply int64
+	}{
+		{mint(alice, 100, keys[0], keys[0]), types.ReceiptStatusFailed, 1500},
+		{mint(alice, 100, keys[0], keys[2]), types.ReceiptStatusSuccessful, 1600},
+		{mint(alice, 200, keys[1], keys[2]), types.ReceiptStatusFailed, 1600},
+		{types.NewBurn(bob, big.NewInt(50)), types.ReceiptStatusSuccessful, 1550},
+	} {
+		insertBlocks(bc, test.tx)
+		receipts := bc.GetReceiptsByHash(bc.CurrentBlock().Hash())
+		if receipts[0].Status != test.status {
+			t.Errorf("block #%d: got status %d, want %d", bc.CurrentBlock().Number().Uint64(), receipts[0].Status, test.status)
+		}
+		if (test.status == types.ReceiptStatusSuccessful) != (len(receipts[0].Logs) == 1) {
+			t.Errorf("block #%d: got receipt logs %v", bc.CurrentBlock().Number().Uint64(), receipts[0].Logs)
+		}
+		if supply := bc.State().TotalSupply(); supply.Int64() != test.supply {
+			t.Errorf("block #%d: got supply %v, want %d", bc.CurrentBlock().Number().Uint64(), supply, test.supply)
+		}
+	}
+	if receipt := bc.GetReceiptsByHash(bc.CurrentBlock().Hash())[0]; receipt.Logs[0].Kind != types.LogBurn || receipt.Logs[0].Account != bob {
+		t.Errorf("got burn record %+v", receipt.Logs[0])
+	}
+}
+
+func TestMintReplay(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	genesis := blockchain.DefaultGenesis()
+	genesis.Config.Issuance.Issuers = nil
+	for i := range keys {
+		var err error
+		if keys[i], err = crypto.GenerateKey(); err != nil {
+			panic(err)
+		}
+		genesis.Config.Issuance.Issuers = append(genesis.Config.Issuance.Issuers, keyAddress(keys[i]))
+	}
+	genesis.Config.Issuance.Threshold = 2
+	bc, cleanup := newTestChainWithGenesis(genesis)
+	defer cleanup()
+
+	tx := mint(alice, 100, keys[0], keys[1])
+	reordered := tx.Copy()
+	reordered.Signature = append(append([]byte{}, tx.Signature[65:]...), tx.Signature[:65]...)
+	duplicated := mint(alice, 100, keys[0], keys[1], keys[1])
+	next := types.NewMint(alice, big.NewInt(100))
+	next.Nonce = 1
+	next, _ = types.AddMintSignature(next, keys[0])
+	next, _ = types.AddMintSignature(next, keys[1])
+
+	if err := genesis.Config.Issuance.Approve(duplicated); err != types.ErrDuplicateApproval {
+		t.Errorf("got approval error %v for a duplicated signature, want %v", err, types.ErrDuplicateApproval)
+	}
+	stranger, _ := crypto.GenerateKey()
+	if err := genesis.Config.Issuance.Approve(mint(alice, 100, keys[0], stranger)); err != types.ErrUnknownIssuer {
+		t.Errorf("got approval error %v for an unknown issuer, want %v", err, types.ErrUnknownIssuer)
+	}
+
+	supply := bc.State().TotalSupply().Int64()
+	for _, test := range []struct {
+		tx     *types.Transaction
+		status uint64
+		supply int64
+	}{
+		{tx, types.ReceiptStatusSuccessful, supply + 100},
+		{tx, types.ReceiptStatusFailed, supply + 100},
+		{reordered, types.ReceiptStatusFailed, supply + 100},
+		{duplicated, types.ReceiptStatusFailed, supply + 100},
+		{next, types.ReceiptStatusSuccessful, supply + 200},
+	} {
+		insertBlocks(bc, test.tx)
+		receipts := bc.GetReceiptsByHash(bc.CurrentBlock().Hash())
+		if receipts[0].Status != test.status {
+			t.Errorf("block #%d: got status %d, want %d", bc.CurrentBlock().Number().Uint64(), receipts[0].Status, test.status)
+		}
+		if got := bc.State().TotalSupply(); got.Int64() != test.supply {
+			t.Errorf("block #%d: got supply %v, want %d", bc.CurrentBlock().Number().Uint64(), got, test.supply)
+		}
+	}
+
+	// the consumed mints are rebuilt from the stored diffs
+	st, err := bc.StateAt(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.IsMinted(tx.MintID()) || st.IsMinted(next.MintID()) {
+		t.Errorf("got consumed mints %v at block #1", st.Mints())
+	}
+	if err := bc.SetHead(2); err != nil {
+		t.Fatal(err)
+	}
+	if !bc.State().IsMinted(tx.MintID()) || bc.State().IsMinted(next.MintID()) {
+		t.Errorf("got consumed mints %v after rewinding to block #2", bc.State().Mints())
+	}
+}
+
+func TestLegacyIssuance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	root := keyAddress(issuerKey)
+	genesis := blockchain.DefaultGenesis()
+	genesis.Config.Issuance.Issuers = []ibft.Address{root}
+	genesis.Config.Issuance.LegacyRoot = root
+	genesis.Config.Issuance.LegacyBlock = 3
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Before the upgrade the root account credits without being debited,
+	// and from it its transfers are paid from its balance
+	insertBlocks(bc,
+		types.NewTransaction(root, alice, big.NewInt(100)),
+		types.NewTransaction(root, bob, big.NewInt(50)),
+		types.NewTransaction(root, bob, big.NewInt(10)),
+		mint(root, 20, issuerKey),
+		types.NewTransaction(root, bob, big.NewInt(10)),
+	)
+	check := func(bc *blockchain.BlockChain) {
+		st := bc.State()
+		for addr, want := range map[ibft.Address]int64{alice: 100, bob: 60, root: 10} {
+			if balance := st.GetBalance(addr); balance.Int64() != want {
+				t.Errorf("got balance %v for %x, want %d", balance, addr, want)
+			}
+		}
+		if stats := st.Stats(); stats.Supply.Int64() != 170 || stats.Minted.Int64() != 170 {
+			t.Errorf("got stats %+v", stats)
+		}
+	}
+	check(bc)
+	for nr, status := range []uint64{types.ReceiptStatusSuccessful, types.ReceiptStatusSuccessful, types.ReceiptStatusFailed} {
+		receipt := bc.GetReceiptsByHash(bc.GetBlockByNumber(uint64(nr + 1)).Hash())[0]
+		if receipt.Status != status || (status == types.ReceiptStatusSuccessful) != (len(receipt.Logs) == 1 && receipt.Logs[0].Kind == types.LogMint) {
+			t.Errorf("block #%d: got receipt %+v", nr+1, receipt)
+		}
+	}
+	bc.Close()
+
+	// The stored chain is replayed with the rule of its genesis
+	if bc, err = blockchain.NewWithGenesis(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	check(bc)
+	if st, err := bc.StateAt(2); err != nil || st.GetBalance(bob).Int64() != 50 {
+		t.Errorf("got state at block #2 %v", err)
+	}
+}
+
+func TestLegacyIssuanceEnds(t *testing.T) {
+	// The main network issuer was its root account before mints existed
+	genesis := blockchain.DefaultGenesis()
+	issuance := genesis.Config.Issuance
+	if issuance.IsLegacyMint(types.NewTransaction(issuance.Issuers[0], alice, big.NewInt(1)), 0) {
+		t.Fatal("the main network genesis enables the legacy issuance")
+	}
+
+	root := ibft.Address{0xff}
+	genesis.Config.Issuance.LegacyRoot = root
+	genesis.Config.Issuance.LegacyBlock = 2
+	bc, cleanup := newTestChainWithGenesis(genesis)
+	defer cleanup()
+
+	// From the legacy block on, transfers of the root account are paid from
+	// its balance like any other
+	insertBlocks(bc,
+		types.NewTransaction(root, alice, big.NewInt(100)),
+		types.NewTransaction(root, bob, big.NewInt(50)),
+		types.NewTransaction(root, bob, big.NewInt(50)),
+	)
+	for nr, want := range []uint64{types.ReceiptStatusSuccessful, types.ReceiptStatusFailed, types.ReceiptStatusFailed} {
+		receipt := bc.GetReceiptsByHash(bc.GetBlockByNumber(uint64(nr + 1)).Hash())[0]
+		if receipt.Status != want {
+			t.Errorf("block #%d: got receipt status %d, want %d", nr+1, receipt.Status, want)
+		}
+	}
+	st := bc.State()
+	if balance := st.GetBalance(bob); balance.Sign() != 0 {
+		t.Errorf("got balance %v for bob", balance)
+	}
+	if stats := st.Stats(); stats.Supply.Int64() != 100 || stats.Minted.Int64() != 100 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/rawdb"
+	"bitbucket.org/ventureslash/go-slash-currency/state"
+)
+
+// InspectDatabase reports the number and size of the entries of the database
+// per kind of data
+func (bc *BlockChain) InspectDatabase() ([]*rawdb.DatabaseStat, error) {
+	return rawdb.InspectDatabase(bc.db)
+}
+
+// CheckIntegrity walks the canonical chain from the genesis block to the head
+// and returns the problems found: missing or unlinked blocks, receipts, state
+// diffs, supply stats and transaction indexes, blocks left above the head, and
+// a supply of the head state differing from its balances.
+func (bc *BlockChain) CheckIntegrity() []error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	problems := []error{}
+	head := bc.CurrentBlock().Number().Uint64()
+	pruned := uint64(0)
+	snapshot := rawdb.ReadStateSnapshot(bc.db)
+	if snapshot != nil {
+		pruned = snapshot.Number.Uint64()
+	}
+
+	parent := ibft.Hash{}
+	for nr := uint64(0); nr <= head; nr++ {
+		hash := rawdb.ReadBlockHash(bc.db, nr)
+		if hash == (ibft.Hash{}) {
+			problems = append(problems, fmt.Errorf("block #%d: no canonical hash", nr))
+			parent = ibft.Hash{}
+			continue
+		}
+		block := rawdb.ReadBlock(bc.db, hash, nr)
+		if block == nil {
+			problems = append(problems, fmt.Errorf("block #%d [%x…]: missing or undecodable", nr, hash.Bytes()[:4]))
+			parent = ibft.Hash{}
+			continue
+		}
+		if block.Hash() != hash {
+			problems = append(problems, fmt.Errorf("block #%d: stored under hash [%x…] but hashes to [%x…]", nr, hash.Bytes()[:4], block.Hash().Bytes()[:4]))
+		}
+		if number := rawdb.ReadBlockNumber(bc.db, hash); number == nil || *number != nr {
+			problems = append(problems, fmt.Errorf("block #%d: missing hash to number mapping", nr))
+		}
+		if nr > 0 && parent != (ibft.Hash{}) && block.ParentHash() != parent {
+			problems = append(problems, fmt.Errorf("block #%d: parent [%x…] is not the canonical block #%d", nr, block.ParentHash().Bytes()[:4], nr-1))
+		}
+		parent = hash
+
+		if receipts := rawdb.ReadReceipts(bc.db, hash, nr); receipts == nil {
+			problems = append(problems, fmt.Errorf("block #%d: missing receipts", nr))
+		} else if len(receipts) != len(block.Transactions) {
+			problems = append(problems, fmt.Errorf("block #%d: %d receipts for %d transactions", nr, len(receipts), len(block.Transactions)))
+		}
+		if (snapshot == nil || nr > pruned) && !rawdb.HasStateDiff(bc.db, hash, nr) {
+			problems = append(problems, fmt.Errorf("block #%d: missing state diff", nr))
+		}
+		if rawdb.ReadSupplyStats(bc.db, hash, nr) == nil {
+			problems = append(problems, fmt.Errorf("block #%d: missing supply stats", nr))
+		}
+		for i, tx := range block.Transactions {
+			entry := rawdb.ReadTxLookupEntry(bc.db, tx.Hash())
+			if entry == nil {
+				problems = append(problems, fmt.Errorf("block #%d: transaction %d is not indexed", nr, i))
+			}
+		}
+	}
+
+	if hash := rawdb.ReadBlockHash(bc.db, head+1); hash != (ibft.Hash{}) {
+		problems = append(problems, fmt.Errorf("block #%d: canonical block above the head #%d", head+1, head))
+	}
+	if err := CheckSupply(bc.state); err != nil {
+		problems = append(problems, fmt.Errorf("block #%d: %v", head, err))
+	}
+	return problems
+}
+
+// CheckSupply verifies the accounting of the money supply of a state: the
+// supply is the sum of the balances and of the minted money minus the burned
+// money, and the accounts holding money are counted
+func CheckSupply(st *state.StateDB) error {
+	stats := st.Stats()
+	sum := new(big.Int)
+	accounts := uint64(0)
+	for _, account := range st.Dump() {
+		sum.Add(sum, account.Balance)
+		if account.Balance.Sign() > 0 {
+			accounts++
+		}
+	}
+	issued := new(big.Int).Sub(stats.Minted, stats.Burned)
+	issued.Sub(issued, stats.DemurrageBurned)
+	switch {
+	case sum.Cmp(stats.Supply) != 0:
+		return fmt.Errorf("supply %v differs from the sum of the balances %v", stats.Supply, sum)
+	case issued.Cmp(stats.Supply) != 0:
+		return fmt.Errorf("supply %v differs from the minted minus the burned money %v", stats.Supply, issued)
+	case accounts != stats.Accounts:
+		return fmt.Errorf("%d accounts counted for %d holding money", stats.Accounts, accounts)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"sync"
+
+	"bitbucket.org/ventureslash/go-slash-currency/metrics"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var (
+	dbMetricsOnce sync.Once
+	dbMetricsMu   sync.Mutex
+	// dbMetricsDB is the chain database whose statistics are exposed: the
+	// last one opened by the process, or nil once it is closed
+	dbMetricsDB *leveldb.DB
+)
+
+// registerDBMetrics exposes the statistics of the chain database. The
+// metrics are registered once per process, as tools and tests open several
+// chains, and follow the last database opened.
+func registerDBMetrics(db *leveldb.DB) {
+	dbMetricsMu.Lock()
+	dbMetricsDB = db
+	dbMetricsMu.Unlock()
+	dbMetricsOnce.Do(func() {
+		metrics.NewRegisteredCounterFunc("slash_leveldb_read_bytes_total", "Bytes read from disk by the chain database.", dbStat(func(s *leveldb.DBStats) float64 {
+			return float64(s.IORead)
+		}))
+		metrics.NewRegisteredCounterFunc("slash_leveldb_write_bytes_total", "Bytes written to disk by the chain database.", dbStat(func(s *leveldb.DBStats) float64 {
+			return float64(s.IOWrite)
+		}))
+		metrics.NewRegisteredGaugeFunc("slash_leveldb_size_bytes", "Total size of the chain database tables.", dbStat(func(s *leveldb.DBStats) float64 {
+			size := int64(0)
+			for _, l := range s.LevelSizes {
+				size += l
+			}
+			return float64(size)
+		}))
+		metrics.NewRegisteredGaugeFunc("slash_leveldb_opened_tables", "Number of tables opened by the chain database.", dbStat(func(s *leveldb.DBStats) float64 {
+			return float64(s.OpenedTablesCount)
+		}))
+		metrics.NewRegisteredCounterFunc("slash_leveldb_write_delays_total", "Number of writes delayed by compactions.", dbStat(func(s *leveldb.DBStats) float64 {
+			return float64(s.WriteDelayCount)
+		}))
+		metrics.NewRegisteredCounterFunc("slash_leveldb_write_delay_seconds_total", "Cumulative time writes were delayed by compactions.", dbStat(func(s *leveldb.DBStats) float64 {
+			return s.WriteDelayDuration.Seconds()
+		}))
+	})
+}
+
+// unregisterDBMetrics stops exposing the statistics of db once it is closed
+func unregisterDBMetrics(db *leveldb.DB) {
+	dbMetricsMu.Lock()
+	defer dbMetricsMu.Unlock()
+	if dbMetricsDB == db {
+		dbMetricsDB = nil
+	}
+}
+
+// dbStat returns a function computing f over the statistics of the exposed
+// chain database, or 0 when there is none
+func dbStat(f func(s *leveldb.DBStats) float64) func() float64 {
+	return func() float64 {
+		dbMetricsMu.Lock()
+		db := dbMetricsDB
+		dbMetricsMu.Unlock()
+		if db == nil {
+			return 0
+		}
+		s := &leveldb.DBStats{}
+		if err := db.Stats(s); err != nil {
+			return 0
+		}
+		return f(s)
+	}
+}
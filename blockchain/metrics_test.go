@@ -0,0 +1,35 @@
+package blockchain_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-slash-currency/metrics"
+)
+
+func TestDBMetrics(t *testing.T) {
+	// Tools and tests open several chains in the same process
+	_, cleanupFirst := newTestChain()
+	defer cleanupFirst()
+	bc, cleanup := newTestChain()
+	defer cleanup()
+	insertBlocks(bc, mint(alice, 100, issuerKey))
+
+	out := &bytes.Buffer{}
+	metrics.DefaultRegistry.Write(out)
+	for _, line := range []string{
+		"# TYPE slash_leveldb_read_bytes_total counter",
+		"# TYPE slash_leveldb_write_bytes_total counter",
+		"# TYPE slash_leveldb_size_bytes gauge",
+		"# TYPE slash_leveldb_write_delays_total counter",
+		"# TYPE slash_leveldb_write_delay_seconds_total counter",
+	} {
+		if n := strings.Count(out.String(), line+"\n"); n != 1 {
+			t.Errorf("got %d lines %q in:\n%s", n, line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "slash_leveldb_write_bytes_total 0\n") {
+		t.Error("the write counter does not follow the last chain opened")
+	}
+}
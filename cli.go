@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/slashclient"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"bitbucket.org/ventureslash/go-slash-currency/wallet"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const commandTimeout = 30 * time.Second
+
+var commandUsage = `Commands:
+  wallet new                          create an encrypted wallet at -w
+  wallet address                      print the address of the wallet at -w
+  wallet import [--mnemonic] [--path m/44'/60'/0'/0/0] <file>
+                                      create the wallet at -w from a hex private key
+                                      or a BIP-39 mnemonic read from file, - for stdin
+  wallet export [--out file]          print the private key of the wallet at -w
+  balance <address> [--node host:port]
+                                      print the balance of an address
+  send [--from wallet] --to <address> --amount <amount> [--reference text] [--node host:port]
+                                      sign a transfer, or a payment carrying a reference
+                                      such as an invoice ID, and submit it to a node
+  pay [--from wallet] <file> [--node host:port]
+                                      sign a batch transfer paying each "<address> <amount>"
+                                      line of file, - for stdin, and submit it
+  burn [--from wallet] --amount <amount> [--node host:port]
+                                      sign a burn of money of the wallet and submit it
+  mint [--from wallet] --to <address> --amount <amount> [--nonce n] [--signatures hex] [--submit]
+                                      approve a mint as an issuer, print the signatures
+                                      for the next issuer or submit them to a node
+`
+
+// usageError is returned when a command is called with invalid arguments
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// runCommand runs the wallet and client subcommands. It returns false when
+// args is not one of them.
+func runCommand(args []string, walletPath string) bool {
+	var err error
+	switch args[0] {
+	case "wallet":
+		if len(args) < 2 {
+			err = usageError("missing wallet command")
+			break
+		}
+		switch args[1] {
+		case "new":
+			err = walletNew(walletPath)
+		case "address":
+			err = walletAddress(walletPath)
+		case "import":
+			err = walletImport(walletPath, args[2:])
+		case "export":
+			err = walletExport(walletPath, args[2:])
+		default:
+			err = usageError(fmt.Sprintf("unknown wallet command %q", args[1]))
+		}
+	case "balance":
+		err = balance(args[1:])
+	case "send":
+		err = send(walletPath, args[1:])
+	case "pay":
+		err = pay(walletPath, args[1:])
+	case "burn":
+		err = burn(walletPath, args[1:])
+	case "mint":
+		err = mint(walletPath, args[1:])
+	case "init":
+		err = initChain(args[1:])
+	case "export":
+		err = exportChain(args[1:])
+	case "import":
+		err = importChain(args[1:])
+	case "inspect":
+		err = inspectChain(args[1:])
+	case "rewind":
+		err = rewindChain(args[1:])
+	default:
+		return false
+	}
+	if _, ok := err.(usageError); ok {
+		fmt.Fprint(os.Stderr, commandUsage+adminUsage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return true
+}
+
+// parseArgs parses the flags of a command, which may follow its positional
+// arguments, and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError(err.Error())
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func walletNew(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return errors.New("a wallet already exists at " + path)
+	}
+	key, err := wallet.New(path)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(wallet.Address(key).Bytes()))
+	return nil
+}
+
+// openWallet loads an existing wallet, where wallet.New would create one
+func openWallet(path string) (*ecdsa.PrivateKey, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return wallet.New(path)
+}
+
+func walletAddress(path string) error {
+	key, err := openWallet(path)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(wallet.Address(key).Bytes()))
+	return nil
+}
+
+func walletImport(path string, args []string) error {
+	fs := flag.NewFlagSet("wallet import", flag.ContinueOnError)
+	mnemonic := fs.Bool("mnemonic", false, "the file holds a BIP-39 mnemonic")
+	mnemonicPassphrase := fs.String("mnemonic-passphrase", "", "optional BIP-39 passphrase of the mnemonic")
+	derivationPath := fs.String("path", wallet.DefaultBaseDerivationPath.Child(0).String(), "BIP-32 derivation path of the key in the mnemonic")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("wallet import takes the file holding the key")
+	}
+
+	var data []byte
+	if positional[0] == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(positional[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	var key *ecdsa.PrivateKey
+	if *mnemonic {
+		hdPath, err := wallet.ParseDerivationPath(*derivationPath)
+		if err != nil {
+			return err
+		}
+		key, err = wallet.FromMnemonic(string(data), *mnemonicPassphrase, hdPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		key, err = crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			return err
+		}
+	}
+
+	passphrase, err := wallet.Passphrase(true)
+	if err != nil {
+		return err
+	}
+	if err := wallet.Create(path, key, passphrase); err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(wallet.Address(key).Bytes()))
+	return nil
+}
+
+func walletExport(path string, args []string) error {
+	fs := flag.NewFlagSet("wallet export", flag.ContinueOnError)
+	out := fs.String("out", "", "file receiving the private key (default: stdout)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	key, err := openWallet(path)
+	if err != nil {
+		return err
+	}
+	data := hex.EncodeToString(crypto.FromECDSA(key)) + "\n"
+	if *out == "" {
+		fmt.Fprintln(os.Stderr, "Warning: the private key is printed unencrypted")
+		fmt.Print(data)
+		return nil
+	}
+	return ioutil.WriteFile(*out, []byte(data), 0600)
+}
+
+// nodeClient returns a client of the node endpoint at host:port, or at a full
+// URL
+func nodeClient(node string, apiKey string) *slashclient.Client {
+	if !strings.Contains(node, "://") {
+		node = "http://" + node
+	}
+	client := slashclient.New(node)
+	if apiKey != "" {
+		client = client.WithAPIKey(apiKey)
+	}
+	return client
+}
+
+func nodeFlags(fs *flag.FlagSet) (node *string, apiKey *string) {
+	node = fs.String("node", "localhost:3000", "endpoint of the node, host:port or URL")
+	apiKey = fs.String("api-key", os.Getenv("SLASH_API_KEY"), "API key of the endpoint (default: $SLASH_API_KEY)")
+	return node, apiKey
+}
+
+func parseAddress(s string) (ibft.Address, error) {
+	bytes, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(bytes) != len(ibft.Address{}) {
+		return ibft.Address{}, fmt.Errorf("invalid address %q", s)
+	}
+	addr := ibft.Address{}
+	addr.FromBytes(bytes)
+	return addr, nil
+}
+
+func balance(args []string) error {
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	node, apiKey := nodeFlags(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("balance takes an address")
+	}
+	addr, err := parseAddress(positional[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	balance, err := nodeClient(*node, *apiKey).Balance(ctx, addr)
+	if err != nil {
+		return err
+	}
+	fmt.Println(balance)
+	return nil
+}
+
+func send(walletPath string, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	from := fs.String("from", walletPath, "wallet of the sender")
+	to := fs.String("to", "", "address of the recipient")
+	amount := fs.String("amount", "", "amount to send")
+	reference := fs.String("reference", "", "reference of the payment, such as the invoice it pays")
+	node, apiKey := nodeFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	recipient, err := parseAddress(*to)
+	if err != nil {
+		return err
+	}
+	value, ok := new(big.Int).SetString(*amount, 10)
+	if !ok || value.Sign() <= 0 {
+		return fmt.Errorf("invalid amount %q", *amount)
+	}
+	if len(*reference) > types.MaxReferenceLength {
+		return types.ErrReferenceTooLong
+	}
+
+	key, err := openWallet(*from)
+	if err != nil {
+		return err
+	}
+	unsigned := types.NewTransaction(wallet.Address(key), recipient, value)
+	if *reference != "" {
+		unsigned = types.NewPayment(wallet.Address(key), recipient, value, []byte(*reference))
+	}
+	tx, err := types.SignTx(unsigned, key)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	hash, err := nodeClient(*node, *apiKey).SendTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(hash.Bytes()))
+	return nil
+}
+
+func pay(walletPath string, args []string) error {
+	fs := flag.NewFlagSet("pay", flag.ContinueOnError)
+	from := fs.String("from", walletPath, "wallet of the sender")
+	node, apiKey := nodeFlags(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("pay takes the file listing the payments")
+	}
+
+	var data []byte
+	if positional[0] == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(positional[0])
+	}
+	if err != nil {
+		return err
+	}
+	outputs := []*types.TxOutput{}
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("line %d: want \"<address> <amount>\"", i+1)
+		}
+		to, err := parseAddress(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %v", i+1, err)
+		}
+		value, ok := new(big.Int).SetString(fields[1], 10)
+		if !ok || value.Sign() <= 0 {
+			return fmt.Errorf("line %d: invalid amount %q", i+1, fields[1])
+		}
+		outputs = append(outputs, &types.TxOutput{To: to, Amount: value})
+	}
+	if len(outputs) == 0 || len(outputs) > types.MaxBatchOutputs {
+		return fmt.Errorf("a batch pays 1 to %d outputs, got %d", types.MaxBatchOutputs, len(outputs))
+	}
+
+	key, err := openWallet(*from)
+	if err != nil {
+		return err
+	}
+	tx, err := types.SignTx(types.NewBatch(wallet.Address(key), outputs), key)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	hash, err := nodeClient(*node, *apiKey).SendTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(hash.Bytes()))
+	return nil
+}
+
+func burn(walletPath string, args []string) error {
+	fs := flag.NewFlagSet("burn", flag.ContinueOnError)
+	from := fs.String("from", walletPath, "wallet of the sender")
+	amount := fs.String("amount", "", "amount to burn")
+	node, apiKey := nodeFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	value, ok := new(big.Int).SetString(*amount, 10)
+	if !ok || value.Sign() <= 0 {
+		return fmt.Errorf("invalid amount %q", *amount)
+	}
+
+	key, err := openWallet(*from)
+	if err != nil {
+		return err
+	}
+	tx, err := types.SignTx(types.NewBurn(wallet.Address(key), value), key)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	hash, err := nodeClient(*node, *apiKey).SendTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(hash.Bytes()))
+	return nil
+}
+
+func mint(walletPath string, args []string) error {
+	fs := flag.NewFlagSet("mint", flag.ContinueOnError)
+	from := fs.String("from", walletPath, "wallet of the issuer")
+	to := fs.String("to", "", "address of the recipient")
+	amount := fs.String("amount", "", "amount to mint")
+	nonce := fs.Uint64("nonce", 0, "nonce telling the mint apart from the committed ones of the same amount to the same account")
+	signatures := fs.String("signatures", "", "hex signatures of the issuers who already approved the mint")
+	submit := fs.Bool("submit", false, "submit the mint to a node instead of printing its signatures")
+	node, apiKey := nodeFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	recipient, err := parseAddress(*to)
+	if err != nil {
+		return err
+	}
+	value, ok := new(big.Int).SetString(*amount, 10)
+	if !ok || value.Sign() <= 0 {
+		return fmt.Errorf("invalid amount %q", *amount)
+	}
+	tx := types.NewMint(recipient, value)
+	tx.Nonce = *nonce
+	if tx.Signature, err = hex.DecodeString(*signatures); err != nil {
+		return fmt.Errorf("invalid signatures: %v", err)
+	}
+
+	key, err := openWallet(*from)
+	if err != nil {
+		return err
+	}
+	if tx, err = types.AddMintSignature(tx, key); err != nil {
+		return err
+	}
+	if !*submit {
+		fmt.Println(hex.EncodeToString(tx.Signature))
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	hash, err := nodeClient(*node, *apiKey).SendTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(hash.Bytes()))
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"flag"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/endpoint"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"bitbucket.org/ventureslash/go-slash-currency/wallet"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func init() {
+	flag.Set("webhooks", "")
+	flag.Set("log-file", "")
+	flag.Set("light-kdf", "true")
+}
+
+// testCurrency serves a blockchain stored in a temporary directory and keeps
+// submitted transactions, with their signature
+type testCurrency struct {
+	bc        *blockchain.BlockChain
+	submitted []*types.Transaction
+}
+
+func (c *testCurrency) DecodeProposal(*ibft.EncodedProposal) (ibft.Proposal, error) { return nil, nil }
+func (c *testCurrency) BlockChain() *blockchain.BlockChain                          { return c.bc }
+func (c *testCurrency) PendingTransactions() []*types.Transaction                   { return c.submitted }
+func (c *testCurrency) GetBalance(addr ibft.Address) *big.Int                       { return c.bc.State().GetBalance(addr) }
+func (c *testCurrency) Status() endpoint.NodeStatus                                 { return endpoint.NodeStatus{} }
+
+func (c *testCurrency) SubmitTransaction(tx *types.Transaction) error {
+	c.submitted = append(c.submitted, tx)
+	return nil
+}
+
+// newTestNode serves an endpoint whose chain holds a block minting 100 to
+// account
+func newTestNode(t *testing.T, account ibft.Address) (*testCurrency, string, func()) {
+	dir, err := ioutil.TempDir("", "cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := blockchain.DefaultGenesis()
+	genesis.Config.Issuance.Issuers = []ibft.Address{wallet.Address(issuerKey)}
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mint, err := types.AddMintSignature(types.NewMint(account, big.NewInt(100)), issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := types.NewBlock(&types.Header{
+		Number:     big.NewInt(1),
+		ParentHash: bc.CurrentBlock().Hash(),
+		Time:       big.NewInt(1),
+	}, types.Transactions{mint})
+	if err := bc.InsertChain([]*types.Block{block}); err != nil {
+		t.Fatal(err)
+	}
+
+	cur := &testCurrency{bc: bc}
+	ep := endpoint.New()
+	ep.Currency = cur
+	server := httptest.NewServer(ep)
+	return cur, server.URL, func() {
+		server.Close()
+		ep.Stop(context.Background())
+		os.RemoveAll(dir)
+	}
+}
+
+// captureStdout returns what fn prints on the standard output
+func captureStdout(t *testing.T, fn func() error) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = fn()
+	os.Stdout = stdout
+	w.Close()
+	out := &bytes.Buffer{}
+	io.Copy(out, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out.String())
+}
+
+func TestParseAddress(t *testing.T) {
+	addr := ibft.Address{1, 2, 3}
+	for _, s := range []string{hex.EncodeToString(addr.Bytes()), "0x" + hex.EncodeToString(addr.Bytes())} {
+		if got, err := parseAddress(s); err != nil || got != addr {
+			t.Errorf("parseAddress(%q) = %x, %v, want %x", s, got, err, addr)
+		}
+	}
+	for _, s := range []string{"", "0x", "zz", hex.EncodeToString(addr.Bytes()[1:])} {
+		if _, err := parseAddress(s); err == nil {
+			t.Errorf("parseAddress(%q) succeeded", s)
+		}
+	}
+}
+
+func TestBalanceAndSend(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := wallet.Address(key)
+	cur, url, cleanup := newTestNode(t, sender)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "cli-wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	walletPath := filepath.Join(dir, "wallet")
+	if err := wallet.Create(walletPath, key, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(wallet.PassphraseEnv, "secret")
+	defer os.Unsetenv(wallet.PassphraseEnv)
+
+	out := captureStdout(t, func() error {
+		return balance([]string{hex.EncodeToString(sender.Bytes()), "--node", url})
+	})
+	if out != "100" {
+		t.Fatalf("balance printed %q, want 100", out)
+	}
+
+	recipient := ibft.Address{9}
+	for _, reference := range []string{"", "invoice 42"} {
+		cur.submitted = nil
+		args := []string{"--to", hex.EncodeToString(recipient.Bytes()), "--amount", "30", "--node", url}
+		if reference != "" {
+			args = append(args, "--reference", reference)
+		}
+		out = captureStdout(t, func() error { return send(walletPath, args) })
+		if len(cur.submitted) != 1 {
+			t.Fatalf("send submitted %d transactions, want 1", len(cur.submitted))
+		}
+		tx := cur.submitted[0]
+		if tx.From != sender || tx.To != recipient || tx.Amount.Int64() != 30 || string(tx.Reference) != reference {
+			t.Fatalf("unexpected submitted transaction: %+v", tx)
+		}
+		if from, err := types.Sender(tx); err != nil || from != sender {
+			t.Fatalf("submitted transaction signed by %x (%v), want %x", from, err, sender)
+		}
+		if reference != "" && tx.Type != types.PaymentTxType {
+			t.Fatalf("transaction with a reference has type %d, want a payment", tx.Type)
+		}
+		if out == "" {
+			t.Fatal("send printed no transaction hash")
+		}
+	}
+
+	if err := send(walletPath, []string{"--to", hex.EncodeToString(recipient.Bytes()), "--amount", "-1", "--node", url}); err == nil {
+		t.Fatal("send accepted a negative amount")
+	}
+}
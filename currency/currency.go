@@ -10,23 +10,24 @@ import (
 	"math/big"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"bitbucket.org/ventureslash/go-ibft"
 	"bitbucket.org/ventureslash/go-ibft/backend"
 	"bitbucket.org/ventureslash/go-ibft/core"
-	"bitbucket.org/ventureslash/go-ibft/crypto"
 	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
 	"bitbucket.org/ventureslash/go-slash-currency/endpoint"
+	"bitbucket.org/ventureslash/go-slash-currency/metrics"
 	"bitbucket.org/ventureslash/go-slash-currency/types"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/google/logger"
 )
 
 const (
-	blockInterval           = 20 * time.Second
 	blockTimeoutTime        = 30 * time.Second
 	blockchainDesyncTimeout = 60 * time.Second
+	submitTimeout           = 5 * time.Second
 )
 
 var (
@@ -36,14 +37,13 @@ var (
 	errInvalidProposal         = errors.New("invalid proposal")
 	errInvalidBlock            = errors.New("invalid block hash")
 	errUnauthorizedTransaction = errors.New("this transaction is not authorized")
-)
+	errTransactionQueueFull    = errors.New("transaction queue is full, try again later")
+	errGenesisMismatch         = errors.New("remote chain has another genesis block")
 
-type transaction struct {
-	From      ibft.Address
-	To        ibft.Address
-	Amount    *big.Int
-	Signature []byte
-}
+	proposerTimeouts = metrics.NewRegisteredCounter("slash_proposer_timeouts_total", "Number of block timeouts that moved on to the next proposer.")
+	syncAttempts     = metrics.NewRegisteredCounterVec("slash_sync_attempts_total", "Number of blockchain synchronizations attempted per remote.", "remote")
+	syncFailures     = metrics.NewRegisteredCounterVec("slash_sync_failures_total", "Number of failed blockchain synchronizations per remote.", "remote")
+)
 
 // Currency initializes currency logic
 type Currency struct {
@@ -60,15 +60,28 @@ type Currency struct {
 	remotes       []string
 	coreRunning   bool
 	waitForValSet bool
+	waitForCA     bool
 	currentSigner uint64
+	lastCommit    time.Time
+	peerHeads     map[string]uint64 // head announced by each state provider
+	dbError       error             // result of the last database write probe
+	dbCheckedAt   time.Time
+	syncClient    *http.Client
+	// statusMu guards the fields reported by Status: coreRunning,
+	// waitForValSet, waitForCA, lastCommit, peerHeads and the database probe
+	statusMu sync.Mutex
 }
 
 // New creates a new currency manager
 func New(config *backend.Config, privateKey *ecdsa.PrivateKey) *Currency {
-	bc, err := blockchain.New(*blockChainDataPath)
+	bc, err := OpenBlockChain()
 	if err != nil {
 		panic("blockchain failure: " + err.Error())
 	}
+	syncClient, err := newSyncClient()
+	if err != nil {
+		panic("sync client failure: " + err.Error())
+	}
 
 	currency := &Currency{
 		txEvents:     make(chan core.CustomEvent),
@@ -76,6 +89,8 @@ func New(config *backend.Config, privateKey *ecdsa.PrivateKey) *Currency {
 		blockchain:   bc,
 		endpoint:     endpoint.New(),
 		logger:       logger.Init("Currency", *verbose, false, ioutil.Discard),
+		peerHeads:    make(map[string]uint64),
+		syncClient:   syncClient,
 	}
 
 	currency.backend = backend.New(config, privateKey, currency, currency.endpoint.EventProxy(), currency.txEvents)
@@ -85,42 +100,45 @@ func New(config *backend.Config, privateKey *ecdsa.PrivateKey) *Currency {
 	currency.currentSigner = 0
 	currency.coreRunning = false
 	currency.waitForValSet = false
+	currency.registerMetrics()
 
 	return currency
 }
 
+func (c *Currency) registerMetrics() {
+	metrics.NewRegisteredGaugeFunc("slash_chain_height", "Number of the head block.", func() float64 {
+		return float64(c.blockchain.CurrentBlock().Number().Uint64())
+	})
+	metrics.NewRegisteredGaugeFunc("slash_seconds_since_last_block", "Seconds elapsed since the timestamp of the head block.", func() float64 {
+		return float64(time.Now().Unix() - c.blockchain.CurrentBlock().Header.Time.Int64())
+	})
+	metrics.NewRegisteredGaugeFunc("slash_pending_transactions", "Number of transactions waiting to be included in a block.", func() float64 {
+		return float64(len(c.transactions))
+	})
+	metrics.NewRegisteredGaugeFunc("slash_validators", "Size of the current validator set.", func() float64 {
+		if c.valSet == nil {
+			return 0
+		}
+		return float64(c.valSet.Size())
+	})
+	metrics.NewRegisteredGaugeFunc("slash_core_running", "Whether the consensus core is running (1) or not (0).", func() float64 {
+		if c.isCoreRunning() {
+			return 1
+		}
+		return 0
+	})
+}
+
 //SyncAndStart synchronize state before startig the currency
 func (c *Currency) SyncAndStart(remotes []string) {
 	c.remotes = remotes
 	for _, remote := range remotes {
 		c.logger.Info("Syncing state from: ", remote)
-		resp, err := http.Get("http://" + remote + "/state")
+		syncAttempts.With(remote).Inc()
+		err := c.importChain(remote, 0, true)
 		if err != nil {
-			c.logger.Warningf("failed to get state from %s: %v", remote, err)
-			continue
-		}
-
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			c.logger.Warningf("failed to read state from %s: %v", remote, err)
-			continue
-		}
-		state := &struct {
-			Blockchain   []*types.Block
-			Transactions []*types.Transaction
-		}{}
-
-		err = rlp.DecodeBytes(body, state)
-		if err != nil {
-			c.logger.Warningf("failed to decode state from %s: %v", remote, err)
-			continue
-		}
-		c.blockchain.ResetWithGenesis(state.Blockchain[0])
-		err = c.blockchain.InsertChain(state.Blockchain[1:])
-
-		if err != nil {
-			c.logger.Warningf("failed to insert blockchain from %s: %v", remote, err)
+			c.logger.Warningf("failed to sync state from %s: %v", remote, err)
+			syncFailures.With(remote).Inc()
 			continue
 		}
 
@@ -135,6 +153,9 @@ func (c *Currency) SyncAndStart(remotes []string) {
 
 // Start makes the currency manager run
 func (c *Currency) Start(isFirstNode bool) {
+	// The endpoint is started first so that probes can report the wait
+	go c.endpoint.Start(":" + os.Getenv("EP_PORT"))
+
 	err := c.waitForCAAuthorization()
 	if err != nil {
 		panic(err)
@@ -143,17 +164,20 @@ func (c *Currency) Start(isFirstNode bool) {
 	c.backend.Start()
 
 	defer c.backend.Stop()
-	go c.endpoint.Start(":" + os.Getenv("EP_PORT"))
 
 	if isFirstNode {
+		if !c.isAuthorizedValidator(c.backend.Address()) {
+			c.logger.Warningf("%s is not a validator of the genesis, other validators will not join it", c.backend.Address().String())
+		}
 		c.setTimer()
 		c.valSet = ibft.NewSet([]ibft.Address{c.backend.Address()})
 		c.backend.StartCore(c.valSet, &ibft.View{
 			Sequence: c.blockchain.CurrentBlock().Number(),
 			Round:    ibft.Big0,
 		})
+		c.setCoreRunning(true)
 	} else {
-		c.waitForValSet = true
+		c.setWaitForValSet(true)
 	}
 	c.desyncTimeout = time.AfterFunc(blockchainDesyncTimeout, c.updateBlockchainSince)
 	c.handleEvent()
@@ -200,8 +224,13 @@ func (c *Currency) Commit(proposal ibft.Proposal) error {
 	if !ok {
 		return errInvalidProposal
 	}
-	receipts, _ := c.blockchain.State().ProcessBlock(block)
-	c.blockchain.WriteBlock(block, receipts)
+	if err := c.blockchain.InsertChain([]*types.Block{block}); err != nil {
+		return err
+	}
+	c.endpoint.PublishBlock(block, c.blockchain.GetReceiptsByHash(block.Hash()))
+	c.statusMu.Lock()
+	c.lastCommit = time.Now()
+	c.statusMu.Unlock()
 
 	c.transactions = types.TxDifference(c.transactions, block.Transactions)
 	if c.blockTimeout != nil {
@@ -270,7 +299,7 @@ func (c *Currency) handleEvent() {
 				c.logger.Warning("decode ValidatorSetEvent failed")
 				continue
 			}
-			if c.waitForValSet && valSetEvent.Dest == c.backend.Address() {
+			if c.isWaitingForValSet() && valSetEvent.Dest == c.backend.Address() {
 				c.logger.Info("Handling ValidatorSetEvent")
 
 				c.handleValidatorSetEvent(valSetEvent)
@@ -282,13 +311,13 @@ func (c *Currency) handleEvent() {
 			c.valSet.RemoveValidator(addr)
 		case ibft.TypeCustomEvents:
 			c.logger.Info("Handling txEvent")
-			tx := transaction{}
-			err := rlp.DecodeBytes(event.Msg, &tx)
+			tx := &types.Transaction{}
+			err := rlp.DecodeBytes(event.Msg, tx)
 			if err != nil {
 				c.logger.Warning("decode transaction failed")
 				continue
 			}
-			if err = verifyTransaction(tx); err != nil {
+			if err = c.verifyTransaction(tx); err != nil {
 				// stop and restart core
 				c.logger.Warning(err)
 				continue
@@ -308,34 +337,43 @@ func (c *Currency) handleValidatorSetEvent(ev core.ValidatorSetEvent) {
 		Sequence: c.blockchain.CurrentBlock().Number(),
 		Round:    ibft.Big0,
 	})
-	c.waitForValSet = false
+	c.setCoreRunning(true)
+	c.setWaitForValSet(false)
 	if c.isProposer() {
 		lastBlockTimestamp := time.Duration(c.blockchain.CurrentBlock().Header.Time.Uint64()) * time.Second
 		now := time.Duration(time.Now().Unix()) * time.Second
-		timeToWait := blockInterval + lastBlockTimestamp - now
+		timeToWait := c.blockInterval() + lastBlockTimestamp - now
 		c.logger.Infof("Wait %d before mining", timeToWait)
 		c.mineTimer = time.AfterFunc(timeToWait, c.mine)
 	}
 }
 
-func verifyTransaction(t transaction) error {
-	txNoSig := transaction{
-		From:      t.From,
-		To:        t.To,
-		Amount:    t.Amount,
-		Signature: []byte{},
+// verifyTransaction checks the signature of a transfer, or the approvals of
+// a mint
+func (c *Currency) verifyTransaction(tx *types.Transaction) error {
+	if tx.IsMint() {
+		if err := c.blockchain.Config().Issuance.Approve(tx); err != nil {
+			return err
+		}
+		if c.blockchain.State().IsMinted(tx.MintID()) {
+			return types.ErrMintReplayed
+		}
+		return nil
 	}
-
-	txNoSigRlp, err := rlp.EncodeToBytes(txNoSig)
-	if err != nil {
+	if tx.Type == types.BatchTxType && (len(tx.Outputs) == 0 || len(tx.Outputs) > types.MaxBatchOutputs) {
+		return types.ErrBatchSize
+	}
+	if err := tx.CheckAmounts(); err != nil {
 		return err
 	}
-
-	addressFrom, err := crypto.GetSignatureAddress(txNoSigRlp, t.Signature)
+	if len(tx.Reference) > types.MaxReferenceLength {
+		return types.ErrReferenceTooLong
+	}
+	addressFrom, err := types.Sender(tx)
 	if err != nil {
 		return err
 	}
-	if addressFrom != t.From {
+	if addressFrom != tx.From {
 		return errUnauthorizedTransaction
 	}
 	return nil
@@ -343,6 +381,7 @@ func verifyTransaction(t transaction) error {
 
 func (c *Currency) updateBlockchainSince() {
 	c.backend.StopCore()
+	c.setCoreRunning(false)
 	c.logger.Info("Blockchain desynchronized, resyncing...")
 
 	c.syncBlockchain()
@@ -353,13 +392,52 @@ func (c *Currency) updateBlockchainSince() {
 		Sequence: c.blockchain.CurrentBlock().Number(),
 		Round:    ibft.Big0,
 	})
+	c.setCoreRunning(true)
 	c.desyncTimeout = time.AfterFunc(blockchainDesyncTimeout, c.updateBlockchainSince)
 
 }
 
-func (c *Currency) addTransactionToList(t transaction) {
-	tx := types.NewTransaction(t.From, t.To, t.Amount)
+func (c *Currency) addTransactionToList(t *types.Transaction) {
+	// Another copy of a mint may be pending with other signatures
+	if t.IsMint() {
+		for _, pending := range c.transactions {
+			if pending.IsMint() && pending.MintID() == t.MintID() {
+				return
+			}
+		}
+	}
+	tx := t.PoolCopy()
 	c.transactions = append(c.transactions, tx)
+	c.endpoint.PublishPendingTransaction(tx)
+}
+
+// SubmitTransaction verifies a signed transaction and queues it with the
+// transactions received from the network. It is included in a block the next
+// time this node proposes one.
+func (c *Currency) SubmitTransaction(tx *types.Transaction) error {
+	if err := c.verifyTransaction(tx); err != nil {
+		return err
+	}
+	msg, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	select {
+	case c.txEvents <- core.CustomEvent{Type: ibft.TypeCustomEvents, Msg: msg}:
+		return nil
+	case <-time.After(submitTimeout):
+		return errTransactionQueueFull
+	}
+}
+
+// OpenBlockChain opens the blockchain stored at the -bc path
+func OpenBlockChain() (*blockchain.BlockChain, error) {
+	return blockchain.New(*blockChainDataPath)
+}
+
+// ChainDataPath returns the -bc path
+func ChainDataPath() string {
+	return *blockChainDataPath
 }
 
 // BlockChain returns the blockchain
@@ -0,0 +1,118 @@
+package currency
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-ibft/core"
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/endpoint"
+	"bitbucket.org/ventureslash/go-slash-currency/slashclient"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestCommittedPaymentSignature(t *testing.T) {
+	flag.Set("log-file", "")
+	dir, err := ioutil.TempDir("", "currency")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	bc, err := blockchain.NewWithGenesis(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	c := &Currency{blockchain: bc, endpoint: endpoint.New()}
+
+	key, _ := crypto.GenerateKey()
+	from := ibft.Address{}
+	from.FromBytes(crypto.PubkeyToAddress(key.PublicKey).Bytes())
+	payment, _ := types.SignTx(types.NewPayment(from, ibft.Address{2}, big.NewInt(1), []byte("invoice 42")), key)
+	if err := c.verifyTransaction(payment); err != nil {
+		t.Fatal(err)
+	}
+	empty, _ := types.SignTx(types.NewPayment(from, ibft.Address{2}, big.NewInt(0), []byte("invoice 43")), key)
+	if err := c.verifyTransaction(empty); err != types.ErrInvalidAmount {
+		t.Fatalf("got %v for a zero amount payment, want %v", err, types.ErrInvalidAmount)
+	}
+	c.addTransactionToList(payment)
+
+	parent := bc.CurrentBlock()
+	block := types.NewBlock(&types.Header{
+		Number:     big.NewInt(1),
+		ParentHash: parent.Hash(),
+		Time:       big.NewInt(1),
+	}, c.transactions)
+	if err := bc.InsertChain([]*types.Block{block}); err != nil {
+		t.Fatal(err)
+	}
+	committed, _, _, _ := bc.GetTransaction(block.Transactions[0].Hash())
+	if committed == nil {
+		t.Fatal("payment not committed")
+	}
+	if sender, err := types.Sender(committed); err != nil || sender != from || string(committed.Reference) != "invoice 42" {
+		t.Fatalf("got sender %x (%v) for the committed payment", sender, err)
+	}
+}
+
+func TestSubmittedPaymentHash(t *testing.T) {
+	flag.Set("log-file", "")
+	dir, err := ioutil.TempDir("", "currency")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	bc, err := blockchain.NewWithGenesis(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	c := &Currency{blockchain: bc, endpoint: endpoint.New(), txEvents: make(chan core.CustomEvent)}
+	c.endpoint.Currency = c
+	server := httptest.NewServer(c.endpoint)
+	defer server.Close()
+
+	// Queued transactions are added to the pool as handleEvent does
+	added := make(chan struct{})
+	go func() {
+		event := <-c.txEvents
+		tx := &types.Transaction{}
+		if err := rlp.DecodeBytes(event.Msg, tx); err != nil {
+			panic(err)
+		}
+		c.addTransactionToList(tx)
+		close(added)
+	}()
+
+	key, _ := crypto.GenerateKey()
+	from := ibft.Address{}
+	from.FromBytes(crypto.PubkeyToAddress(key.PublicKey).Bytes())
+	payment, _ := types.SignTx(types.NewPayment(from, ibft.Address{2}, big.NewInt(1), []byte("invoice 42")), key)
+	hash, err := slashclient.New(server.URL).SendTransaction(context.Background(), payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-added
+
+	block := types.NewBlock(&types.Header{
+		Number:     big.NewInt(1),
+		ParentHash: bc.CurrentBlock().Hash(),
+		Time:       big.NewInt(1),
+	}, c.transactions)
+	if err := bc.InsertChain([]*types.Block{block}); err != nil {
+		t.Fatal(err)
+	}
+	committed, _, number, _ := bc.GetTransaction(hash)
+	if committed == nil || number != 1 || string(committed.Reference) != "invoice 42" {
+		t.Fatalf("payment not found by the submitted hash %x", hash)
+	}
+}
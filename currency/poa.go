@@ -23,11 +23,17 @@ func (c *Currency) mine() {
 }
 
 func (c *Currency) setTimer() {
-	c.mineTimer = time.AfterFunc(blockInterval, c.mine)
+	c.mineTimer = time.AfterFunc(c.blockInterval(), c.mine)
+}
+
+// blockInterval returns the time between two blocks set by the genesis
+func (c *Currency) blockInterval() time.Duration {
+	return time.Duration(c.blockchain.Config().BlockInterval) * time.Second
 }
 
 func (c *Currency) handleTimeout() {
 	c.logger.Warning("Block timeout, next proposer")
+	proposerTimeouts.Inc()
 	c.currentSigner++
 	c.blockTimeout = time.AfterFunc(blockTimeoutTime, c.handleTimeout)
 	if c.isProposer() {
@@ -52,6 +58,14 @@ func (c *Currency) waitForCAAuthorization() error {
 		return errFailedToContactCA
 	}
 	c.logger.Infof("Got Authorisation to start at block %d", startingBlock)
+	c.statusMu.Lock()
+	c.waitForCA = true
+	c.statusMu.Unlock()
+	defer func() {
+		c.statusMu.Lock()
+		c.waitForCA = false
+		c.statusMu.Unlock()
+	}()
 
 	// Wait for this currencyBlock.Number >= startingBlock while syncing th bc
 	for currentBlock := c.blockchain.CurrentBlock().Number().Uint64(); currentBlock < startingBlock; {
@@ -67,7 +81,7 @@ func (c *Currency) waitForCAAuthorization() error {
 }
 
 func (c *Currency) isAuthorizedValidator(addr ibft.Address) bool {
-	return true
+	return c.blockchain.Genesis().IsValidator(addr)
 	// TODO: http.Get("<ca url>/peer-authorization/" + addr) -> { addr: addr, startingBlock: 53 }
 	// return currentBlock < res.startingBlock
 }
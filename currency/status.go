@@ -0,0 +1,75 @@
+package currency
+
+import (
+	"time"
+
+	"bitbucket.org/ventureslash/go-slash-currency/endpoint"
+)
+
+// dbCheckInterval is how long the result of a database write probe is reused,
+// so that frequent health probes do not write to the database each time
+const dbCheckInterval = 10 * time.Second
+
+// setCoreRunning records whether the consensus core runs
+func (c *Currency) setCoreRunning(running bool) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.coreRunning = running
+}
+
+func (c *Currency) isCoreRunning() bool {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.coreRunning
+}
+
+// setWaitForValSet records whether the node waits for the validator set of
+// its peers
+func (c *Currency) setWaitForValSet(waiting bool) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.waitForValSet = waiting
+}
+
+func (c *Currency) isWaitingForValSet() bool {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.waitForValSet
+}
+
+// recordPeerHead remembers the head announced by a state provider
+func (c *Currency) recordPeerHead(remote string, head uint64) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.peerHeads[remote] = head
+}
+
+// Status reports the consensus and synchronization state of the node
+func (c *Currency) Status() endpoint.NodeStatus {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	head := c.blockchain.CurrentBlock()
+	status := endpoint.NodeStatus{
+		CoreRunning:               c.coreRunning,
+		WaitingForValSet:          c.waitForValSet,
+		WaitingForCAAuthorization: c.waitForCA,
+		LastCommit:                c.lastCommit,
+		DesyncTimeout:             blockchainDesyncTimeout,
+		Head:                      head.Number().Uint64(),
+	}
+	if time.Since(c.dbCheckedAt) >= dbCheckInterval {
+		c.dbError = c.blockchain.CheckWritable()
+		c.dbCheckedAt = time.Now()
+	}
+	status.DBError = c.dbError
+	if status.LastCommit.IsZero() {
+		status.LastCommit = time.Unix(head.Header.Time.Int64(), 0)
+	}
+	for _, peerHead := range c.peerHeads {
+		if peerHead > status.BestPeerHead {
+			status.BestPeerHead = peerHead
+		}
+	}
+	return status
+}
@@ -0,0 +1,52 @@
+package currency
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+)
+
+func TestStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "currency")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	bc, err := blockchain.NewWithGenesis(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Currency{blockchain: bc, peerHeads: make(map[string]uint64)}
+
+	c.setWaitForValSet(true)
+	c.recordPeerHead("a", 12)
+	c.recordPeerHead("b", 30)
+	c.recordPeerHead("a", 20)
+	status := c.Status()
+	if status.CoreRunning || !status.WaitingForValSet || status.BestPeerHead != 30 || status.DBError != nil {
+		t.Fatalf("got status %+v", status)
+	}
+	// Before the first commit, the head block is the last one
+	if status.LastCommit.Unix() != bc.CurrentBlock().Header.Time.Int64() {
+		t.Errorf("got last commit %v", status.LastCommit)
+	}
+
+	c.setCoreRunning(true)
+	c.setWaitForValSet(false)
+	if status := c.Status(); !status.CoreRunning || status.WaitingForValSet {
+		t.Fatalf("got status %+v", status)
+	}
+
+	// The database probe is reused until dbCheckInterval elapsed
+	bc.Close()
+	if err := c.Status().DBError; err != nil {
+		t.Fatalf("database probed again before the interval: %v", err)
+	}
+	c.dbCheckedAt = time.Now().Add(-dbCheckInterval)
+	if err := c.Status().DBError; err == nil {
+		t.Fatal("closed database reported writable")
+	}
+}
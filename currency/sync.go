@@ -1,46 +1,127 @@
 package currency
 
 import (
-	"io/ioutil"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
 	"bitbucket.org/ventureslash/go-slash-currency/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
+// syncIdleTimeout bounds the wait for the next bytes of a chain stream
+const syncIdleTimeout = time.Minute
+
+var errSyncStalled = errors.New("state provider stopped sending the chain")
+
+// idleReader closes a response body when nothing was read from it for a
+// while, so that a stalled state provider does not block the sync forever
+type idleReader struct {
+	body    io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	stalled int32
+}
+
+func newIdleReader(body io.ReadCloser, timeout time.Duration) *idleReader {
+	r := &idleReader{body: body, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&r.stalled, 1)
+		body.Close()
+	})
+	return r
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if atomic.LoadInt32(&r.stalled) == 1 {
+		return n, errSyncStalled
+	}
+	r.timer.Reset(r.timeout)
+	return n, err
+}
+
+// Close stops the idle timer and closes the body
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	return r.body.Close()
+}
+
 func (c *Currency) syncBlockchain() {
+	head := c.blockchain.CurrentBlock().Number().Uint64()
+	c.endpoint.PublishSyncing(true, head)
+	defer func() {
+		c.publishBlocksSince(head)
+		c.endpoint.PublishSyncing(false, c.blockchain.CurrentBlock().Number().Uint64())
+	}()
+
 	for _, remote := range c.remotes {
 		c.logger.Info("Syncing blockchain from: ", remote)
-		// TODO: only request the missing blocks
-		resp, err := http.Get("http://" + remote + "/state")
+		syncAttempts.With(remote).Inc()
+		err := c.importChain(remote, c.blockchain.CurrentBlock().Number().Uint64()+1, false)
 		if err != nil {
-			c.logger.Warningf("failed to get state from %s: %v", remote, err)
+			c.logger.Warningf("failed to sync blockchain from %s: %v", remote, err)
+			syncFailures.With(remote).Inc()
 			continue
 		}
 
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			c.logger.Warningf("failed to read state from %s: %v", remote, err)
-			continue
-		}
-		state := &struct {
-			Blockchain   []*types.Block
-			Transactions []*types.Transaction
-		}{}
+		// No error triggered a continue. The blockchain is synchronized
+		return
+	}
+}
 
-		err = rlp.DecodeBytes(body, state)
-		if err != nil {
-			c.logger.Warningf("failed to decode state from %s: %v", remote, err)
-			continue
+// importChain streams the blocks of a state provider, starting at block from,
+// into the blockchain. When reset is set, the blockchain is first reset to the
+// genesis block of the provider. A fresh chain adopts the genesis of the
+// provider, unless it was chosen with -genesis.
+func (c *Currency) importChain(remote string, from uint64, reset bool) error {
+	resp, err := c.syncClient.Get(syncURL(remote, fmt.Sprintf("/export?from=%d", from)))
+	if err != nil {
+		return err
+	}
+	stream := newIdleReader(resp.Body, syncIdleTimeout)
+	defer stream.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("export failed: %s", resp.Status)
+	}
+	if head, err := strconv.ParseUint(resp.Header.Get("X-Chain-Head"), 10, 64); err == nil {
+		c.recordPeerHead(remote, head)
+	}
+
+	// The buffered reader is shared by the genesis decoding and the import
+	body := bufio.NewReader(stream)
+	if reset {
+		genesis := new(types.Block)
+		if err := rlp.Decode(body, genesis); err != nil {
+			return fmt.Errorf("failed to decode genesis block: %v", err)
 		}
-		err = c.blockchain.InsertChain(state.Blockchain[c.blockchain.CurrentBlock().Number().Uint64()+1:])
-		if err != nil {
-			c.logger.Warningf("failed to insert blockchain from %s: %v", remote, err)
-			continue
+		if genesis.Hash() != c.blockchain.GetBlockByNumber(0).Hash() &&
+			(blockchain.GenesisConfigured() || c.blockchain.CurrentBlock().Number().Sign() > 0) {
+			return errGenesisMismatch
 		}
+		if err := c.blockchain.ResetWithGenesis(genesis); err != nil {
+			return err
+		}
+	}
+	imported, err := c.blockchain.Import(body)
+	c.logger.Infof("Imported %d blocks from %s", imported, remote)
+	return err
+}
 
-		// No error triggered a continue. The blockchain is synchronized
-		return
+// publishBlocksSince notifies the endpoint subscribers of the blocks inserted
+// after the given block number
+func (c *Currency) publishBlocksSince(number uint64) {
+	for n := number + 1; n <= c.blockchain.CurrentBlock().Number().Uint64(); n++ {
+		block := c.blockchain.GetBlockByNumber(n)
+		if block == nil {
+			return
+		}
+		c.endpoint.PublishBlock(block, c.blockchain.GetReceiptsByHash(block.Hash()))
 	}
 }
@@ -0,0 +1,95 @@
+package currency
+
+import (
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/endpoint"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/google/logger"
+)
+
+// newTestChain opens a chain created from genesis in a temporary directory
+// and appends the given number of empty blocks to it
+func newTestChain(t *testing.T, genesis *blockchain.Genesis, blocks int) (*blockchain.BlockChain, func()) {
+	dir, err := ioutil.TempDir("", "currency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := bc.CurrentBlock()
+	for i := 1; i <= blocks; i++ {
+		block := types.NewBlock(&types.Header{
+			Number:     big.NewInt(int64(i)),
+			ParentHash: parent.Hash(),
+			Time:       big.NewInt(int64(i)),
+		}, types.Transactions{})
+		if err := bc.InsertChain([]*types.Block{block}); err != nil {
+			t.Fatal(err)
+		}
+		parent = block
+	}
+	return bc, func() {
+		bc.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestImportChainGenesis(t *testing.T) {
+	flag.Set("log-file", "")
+	genesis := blockchain.DefaultGenesis()
+	genesis.Timestamp++
+	remoteChain, cleanup := newTestChain(t, genesis, 3)
+	defer cleanup()
+	remote := &Currency{blockchain: remoteChain, endpoint: endpoint.New()}
+	remote.endpoint.Currency = remote
+	server := httptest.NewServer(remote.endpoint)
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	newLocal := func(blocks int) (*Currency, func()) {
+		bc, cleanup := newTestChain(t, nil, blocks)
+		return &Currency{
+			blockchain: bc,
+			syncClient: http.DefaultClient,
+			peerHeads:  make(map[string]uint64),
+			logger:     logger.Init("Currency", false, false, ioutil.Discard),
+		}, cleanup
+	}
+
+	// A fresh node adopts the genesis of the network it syncs from
+	c, cleanup := newLocal(0)
+	defer cleanup()
+	if err := c.importChain(addr, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if head := c.blockchain.CurrentBlock(); head.Hash() != remoteChain.CurrentBlock().Hash() {
+		t.Fatalf("got head #%d after the sync of a fresh chain", head.Number())
+	}
+
+	// A chain with blocks of its own keeps its genesis
+	c, cleanup = newLocal(1)
+	defer cleanup()
+	if err := c.importChain(addr, 0, true); err != errGenesisMismatch {
+		t.Fatalf("got %v syncing a chain past its genesis, want %v", err, errGenesisMismatch)
+	}
+
+	// So does a fresh chain created from the -genesis file
+	defer flag.Set("genesis", "")
+	flag.Set("genesis", "genesis.json")
+	c, cleanup = newLocal(0)
+	defer cleanup()
+	if err := c.importChain(addr, 0, true); err != errGenesisMismatch {
+		t.Fatalf("got %v syncing a chain of the configured genesis, want %v", err, errGenesisMismatch)
+	}
+}
@@ -0,0 +1,55 @@
+package currency
+
+import (
+	"crypto/tls"
+	"flag"
+	"net/http"
+	"time"
+
+	"bitbucket.org/ventureslash/go-slash-currency/endpoint"
+)
+
+const syncRequestTimeout = 5 * time.Minute
+
+var (
+	syncTLS      = flag.Bool("sync-tls", false, "use https to sync from state providers")
+	syncCAFile   = flag.String("sync-ca", "", "CA bundle verifying the certificates of state providers (implies -sync-tls)")
+	syncCertFile = flag.String("sync-cert", "", "client certificate presented to state providers requiring mutual TLS")
+	syncKeyFile  = flag.String("sync-key", "", "private key of the -sync-cert client certificate")
+)
+
+// syncURL returns the url of a route of a state provider
+func syncURL(remote string, route string) string {
+	if *syncTLS || *syncCAFile != "" {
+		return "https://" + remote + route
+	}
+	return "http://" + remote + route
+}
+
+// newSyncClient returns the http client used to sync from state providers
+func newSyncClient() (*http.Client, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if *syncCAFile != "" {
+		pool, err := endpoint.LoadCertPool(*syncCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if *syncCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(*syncCertFile, *syncKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	// Chains are streamed and may take longer than the timeout to download,
+	// so only the wait for the response headers is bounded here. importChain
+	// bounds the wait for the body with syncIdleTimeout.
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:       config,
+			ResponseHeaderTimeout: syncRequestTimeout,
+		},
+	}, nil
+}
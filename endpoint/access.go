@@ -0,0 +1,272 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scopes granted to API keys. Each route requires one of them.
+const (
+	scopeNone   = ""       // probes, always reachable
+	scopeRead   = "read"   // public chain reads
+	scopeSubmit = "submit" // transaction submission
+	scopeSync   = "sync"   // chain synchronization between peers
+	scopeAdmin  = "admin"  // logs, peers, webhooks and metrics
+)
+
+var apiConfigPath = flag.String("api-config", "", "endpoint access configuration file (API keys, rate limits and CORS)")
+
+// APIKey describes what the bearer of a key is allowed to do. A zero rate
+// disables rate limiting for the key.
+type APIKey struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	Rate   float64  `json:"rate"`
+	Burst  int      `json:"burst"`
+}
+
+// AccessConfig is the content of the file given by -api-config
+type AccessConfig struct {
+	// API keys, indexed by token
+	Keys map[string]APIKey `json:"keys"`
+	// Scopes granted to requests without a key
+	PublicScopes []string `json:"publicScopes"`
+	// Origins allowed for CORS and websockets, "*" allows any origin
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// Requests per second allowed for each client IP, 0 disables the limit
+	IPRate  float64 `json:"ipRate"`
+	IPBurst int     `json:"ipBurst"`
+}
+
+// defaultAccessConfig keeps reads and submissions public but requires a key
+// for admin routes.
+func defaultAccessConfig() *AccessConfig {
+	return &AccessConfig{
+		Keys:           map[string]APIKey{},
+		PublicScopes:   []string{scopeRead, scopeSubmit},
+		AllowedOrigins: []string{"*"},
+	}
+}
+
+func loadAccessConfig(path string) (*AccessConfig, error) {
+	if path == "" {
+		return defaultAccessConfig(), nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := defaultAccessConfig()
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// accessControl enforces the access configuration on every route
+type accessControl struct {
+	config  *AccessConfig
+	ipLimit *rateLimiter
+	keyLims map[string]*rateLimiter
+}
+
+func newAccessControl(config *AccessConfig) *accessControl {
+	ac := &accessControl{
+		config:  config,
+		keyLims: make(map[string]*rateLimiter),
+	}
+	if config.IPRate > 0 {
+		ac.ipLimit = newRateLimiter(config.IPRate, config.IPBurst)
+	}
+	for token, key := range config.Keys {
+		if key.Rate > 0 {
+			ac.keyLims[token] = newRateLimiter(key.Rate, key.Burst)
+		}
+	}
+	return ac
+}
+
+// token extracts the API key of a request. Browsers cannot set headers on
+// websocket connections, so the key is also accepted as a query parameter.
+func token(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin reports whether a browser origin is allowed. Requests without
+// an origin do not come from a browser and are always allowed.
+func (ac *accessControl) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range ac.config.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (ac *accessControl) setCorsHeaders(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !ac.checkOrigin(r) {
+		return
+	}
+	if hasScope(ac.config.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, X-API-Key, Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+}
+
+// wrap enforces CORS, authentication and rate limits before calling handler
+func (ac *accessControl) wrap(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac.setCorsHeaders(w, r)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !ac.checkOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		if scope == scopeNone {
+			handler(w, r)
+			return
+		}
+
+		if ac.ipLimit != nil && !ac.ipLimit.allow(clientIP(r)) {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		// Sync routes are granted to the readers of the chain, but only to
+		// authenticated peers when mutual TLS is configured.
+		required := scope
+		if scope == scopeSync {
+			if *tlsClientCAFile != "" && !hasPeerCertificate(r) {
+				http.Error(w, "peer certificate required", http.StatusForbidden)
+				return
+			}
+			required = scopeRead
+		}
+
+		scopes := ac.config.PublicScopes
+		if t := token(r); t != "" {
+			key, ok := ac.config.Keys[t]
+			if !ok {
+				http.Error(w, "invalid API key", http.StatusUnauthorized)
+				return
+			}
+			if lim, ok := ac.keyLims[t]; ok && !lim.allow(t) {
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			scopes = append(append([]string{}, scopes...), key.Scopes...)
+		}
+		if !hasScope(scopes, required) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing scope: "+required, http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// bucketSweepInterval is how often a rate limiter forgets its idle clients
+const bucketSweepInterval = time.Minute
+
+// rateLimiter is a set of token buckets indexed by client
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow consumes a token from the bucket of a client if one is available
+func (l *rateLimiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= bucketSweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep forgets the clients whose bucket refilled, which a new bucket
+// replaces with the same tokens. Note, this function assumes that the mu
+// mutex is held!
+func (l *rateLimiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
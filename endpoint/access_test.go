@@ -0,0 +1,92 @@
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(ac *accessControl, scope string, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	ac.wrap(scope, func(w http.ResponseWriter, r *http.Request) {})(rec, req)
+	return rec
+}
+
+func TestAccessScopes(t *testing.T) {
+	config := defaultAccessConfig()
+	config.Keys["secret"] = APIKey{Name: "ops", Scopes: []string{scopeAdmin}}
+	ac := newAccessControl(config)
+
+	req := httptest.NewRequest("GET", "/logs", nil)
+	if code := serve(ac, scopeAdmin, req).Code; code != http.StatusUnauthorized {
+		t.Fatalf("admin route without key: got %d", code)
+	}
+	if code := serve(ac, scopeRead, req).Code; code != http.StatusOK {
+		t.Fatalf("public route without key: got %d", code)
+	}
+
+	req.Header.Set("Authorization", "Bearer secret")
+	if code := serve(ac, scopeAdmin, req).Code; code != http.StatusOK {
+		t.Fatalf("admin route with key: got %d", code)
+	}
+
+	req.Header.Set("Authorization", "Bearer nope")
+	if code := serve(ac, scopeRead, req).Code; code != http.StatusUnauthorized {
+		t.Fatalf("invalid key: got %d", code)
+	}
+}
+
+func TestAccessRateLimit(t *testing.T) {
+	config := defaultAccessConfig()
+	config.IPRate, config.IPBurst = 1, 2
+	ac := newAccessControl(config)
+
+	req := httptest.NewRequest("GET", "/chain", nil)
+	for i := 0; i < 2; i++ {
+		if code := serve(ac, scopeRead, req).Code; code != http.StatusOK {
+			t.Fatalf("request %d within burst: got %d", i, code)
+		}
+	}
+	if code := serve(ac, scopeRead, req).Code; code != http.StatusTooManyRequests {
+		t.Fatalf("request over burst: got %d", code)
+	}
+}
+
+func TestAccessOrigins(t *testing.T) {
+	config := defaultAccessConfig()
+	config.AllowedOrigins = []string{"https://explorer.example"}
+	ac := newAccessControl(config)
+
+	req := httptest.NewRequest("GET", "/chain", nil)
+	req.Header.Set("Origin", "https://explorer.example")
+	rec := serve(ac, scopeRead, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://explorer.example" {
+		t.Fatalf("allowed origin: got %d %v", rec.Code, rec.Header())
+	}
+
+	req.Header.Set("Origin", "https://evil.example")
+	if code := serve(ac, scopeRead, req).Code; code != http.StatusForbidden {
+		t.Fatalf("forbidden origin: got %d", code)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l := newRateLimiter(1, 2)
+	for _, client := range []string{"a", "b", "c"} {
+		l.allow(client)
+	}
+	l.allow("c")
+	l.allow("c")
+
+	// Refilled buckets are forgotten, the others keep their tokens
+	l.buckets["a"].last = l.buckets["a"].last.Add(-time.Second)
+	l.lastSweep = l.lastSweep.Add(-bucketSweepInterval)
+	l.allow("b")
+	if _, ok := l.buckets["a"]; ok || len(l.buckets) != 2 {
+		t.Fatalf("got %d buckets after a sweep", len(l.buckets))
+	}
+	if l.allow("c") {
+		t.Fatal("sweep refilled the bucket of an active client")
+	}
+}
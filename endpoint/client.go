@@ -2,9 +2,11 @@ package endpoint
 
 import (
 	"encoding/json"
-	"github.com/gorilla/websocket"
 	"net/http"
 	"time"
+
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -19,12 +21,11 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
-)
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-}
+	// Messages queued for a client. It holds the logs of a full batch
+	// transfer, so that the subscribers of its sender are not dropped.
+	sendBufferSize = 2 * types.MaxBatchOutputs
+)
 
 // Client is a middleman between the websocket connection and the ep.
 type Client struct {
@@ -33,8 +34,33 @@ type Client struct {
 	// The websocket connection.
 	conn *websocket.Conn
 
-	// Buffered channel of outbound messages.
+	// Buffered channel of outbound messages, closed by the hub. Only the hub
+	// sends on it once the client is registered.
 	send chan interface{}
+
+	// Topics the client subscribed to.
+	subs *subscriptions
+}
+
+// reply is a message of a read pump to its client, sent by the hub
+type reply struct {
+	client *Client
+	msg    message
+}
+
+// reply hands msg to the hub, which drops it if the client is gone
+func (c *Client) reply(msg message) {
+	select {
+	case c.ep.replies <- &reply{client: c, msg: msg}:
+	case <-c.ep.quit:
+	}
+}
+
+func (c *Client) sendError(reason string) {
+	c.reply(message{
+		Type: "error",
+		Data: reason,
+	})
 }
 
 // readPump pumps messages from the websocket connection to the ep.
@@ -44,7 +70,10 @@ type Client struct {
 // reads from this goroutine.
 func (c *Client) readPump() {
 	defer func() {
-		c.ep.unregister <- c
+		select {
+		case c.ep.unregister <- c:
+		case <-c.ep.quit:
+		}
 		c.conn.Close()
 	}()
 	c.conn.SetReadLimit(maxMessageSize)
@@ -59,10 +88,11 @@ func (c *Client) readPump() {
 			break
 		}
 		// Parse the message (client request)
-		var msg message
+		var msg request
 		err = json.Unmarshal(msgBytes, &msg)
 		if err != nil {
 			c.ep.debug.Warningf("Invalid json received: %v", err)
+			continue
 		}
 
 		c.ep.handleMsg(&msg, c)
@@ -80,6 +110,7 @@ func (c *Client) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.ep.clientsWg.Done()
 	}()
 	for {
 		select {
@@ -114,19 +145,25 @@ func (c *Client) writePump() {
 
 // serveWs handles websocket requests from the peer.
 func serveWs(ep *Endpoint, w http.ResponseWriter, r *http.Request) {
-	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := ep.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		ep.debug.Warningf("connection upgrade failed: %v", err)
 		return
 	}
-	client := &Client{ep: ep, conn: conn, send: make(chan interface{}, 256)}
-	client.ep.register <- client
-
+	client := &Client{ep: ep, conn: conn, send: make(chan interface{}, sendBufferSize), subs: newSubscriptions()}
 	client.send <- message{
 		Type: "connection",
 		Data: "connected",
 	}
+	ep.clientsWg.Add(1)
+	select {
+	case client.ep.register <- client:
+	case <-ep.quit:
+		conn.Close()
+		ep.clientsWg.Done()
+		return
+	}
+
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
 	go client.writePump()
@@ -1,6 +1,7 @@
 package endpoint
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"flag"
@@ -9,6 +10,8 @@ import (
 	"math/big"
 	"net/http"
 	"reflect"
+	"strconv"
+	"sync"
 
 	"bitbucket.org/ventureslash/go-ibft"
 	"bitbucket.org/ventureslash/go-ibft/backend"
@@ -18,6 +21,8 @@ import (
 	"github.com/coryb/gotee"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/google/logger"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
 )
 
 type currency interface {
@@ -25,11 +30,19 @@ type currency interface {
 	BlockChain() *blockchain.BlockChain
 	PendingTransactions() []*types.Transaction
 	GetBalance(addr ibft.Address) *big.Int
+	SubmitTransaction(tx *types.Transaction) error
+	Status() NodeStatus
 }
 
-const logFile = "slash-currency.logs"
+var (
+	verbose = flag.Bool("verbose-endpoint", false, "print endpoint info level logs")
+	logFile = flag.String("log-file", "slash-currency.logs", "file receiving a copy of the node output, served at /logs (empty disables it)")
 
-var verbose = flag.Bool("verbose-endpoint", false, "print endpoint info level logs")
+	// The node output is teed once per process, whatever the number of
+	// endpoints
+	tee     *gotee.Tee
+	teeOnce sync.Once
+)
 
 // Endpoint maintains the set of active clients and broadcasts messages to the
 // clients.
@@ -42,10 +55,32 @@ type Endpoint struct {
 	register chan *Client
 	// Unregister requests from clients.
 	unregister chan *Client
+	// Events dispatched to the clients subscriptions.
+	notifications chan *notification
+	// Replies of the read pumps to their clients.
+	replies chan *reply
 	// A function that returns a mapping of connected clients
 	networkMapGetter func() map[ibft.Address]string
-	// log tee
-	tee *gotee.Tee
+	// Registered webhooks, nil when disabled
+	webhooks *webhookRegistry
+	// API keys, rate limits and allowed origins
+	access *accessControl
+	// Schema of the queries served at /graphql
+	schema *graphql.Schema
+	// Routes of the endpoint
+	mux *http.ServeMux
+	// Upgrades /ws requests to websocket connections
+	upgrader websocket.Upgrader
+	// Running http server, nil until Start is called
+	server   *http.Server
+	serverMu sync.Mutex
+	// Closed by Stop to shut the hub and the webhook worker down
+	quit     chan struct{}
+	quitOnce sync.Once
+	// Tracks the running websocket clients
+	clientsWg sync.WaitGroup
+	// Tracks the webhook worker, which writes to the webhook database
+	webhooksWg sync.WaitGroup
 
 	Currency currency
 	Backend  *backend.Backend
@@ -58,37 +93,93 @@ func New() *Endpoint {
 		broadcast:        make(chan interface{}),
 		register:         make(chan *Client),
 		unregister:       make(chan *Client),
+		notifications:    make(chan *notification, 256),
+		replies:          make(chan *reply),
 		clients:          make(map[*Client]bool),
 		networkMapGetter: nil,
+		mux:              http.NewServeMux(),
+		quit:             make(chan struct{}),
 		debug:            logger.Init("Endpoint", *verbose, false, ioutil.Discard),
 	}
 
-	ep.tee, _ = gotee.NewTee(logFile)
+	if *logFile != "" {
+		teeOnce.Do(func() { tee, _ = gotee.NewTee(*logFile) })
+	}
 
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+	accessConfig, err := loadAccessConfig(*apiConfigPath)
+	if err != nil {
+		panic("api config failure: " + err.Error())
+	}
+	ep.access = newAccessControl(accessConfig)
+	ep.schema = newGraphQLSchema(ep)
+	ep.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     ep.access.checkOrigin,
+	}
+
+	if ep.webhooks, err = openWebhookRegistry(); err != nil {
+		ep.debug.Errorf("failed to open webhook registry: %v", err)
+	}
+
+	ep.handleFunc("/ws", scopeRead, func(w http.ResponseWriter, r *http.Request) {
 		serveWs(ep, w, r)
 	})
 
-	http.HandleFunc("/hello", ep.helloHandler)
-	http.HandleFunc("/logs", ep.logsHandler)
-	http.HandleFunc("/state", ep.stateHandler)
-	http.HandleFunc("/balance", ep.balanceHandler)
-	http.HandleFunc("/chain", ep.chainHandler)
+	ep.handleFunc("/hello", scopeRead, ep.helloHandler)
+	ep.handleFunc("/logs", scopeAdmin, ep.logsHandler)
+	ep.handleFunc("/state", scopeSync, ep.stateHandler)
+	ep.handleFunc("/export", scopeSync, ep.exportHandler)
+	ep.handleFunc("/import", scopeAdmin, ep.importHandler)
+	ep.handleFunc("/balance", scopeRead, ep.balanceHandler)
+	ep.handleFunc("/chain", scopeRead, ep.chainHandler)
+	ep.handleFunc("/explorer", scopeRead, ep.explorerHandler)
+	ep.handleFunc("/blocks", scopeRead, ep.blocksHandler)
+	ep.handleFunc("/block", scopeRead, ep.blockHandler)
+	ep.handleFunc("/tx", scopeRead, ep.txHandler)
+	ep.handleFunc("/receipt", scopeRead, ep.receiptHandler)
+	ep.handleFunc("/submit", scopeSubmit, ep.submitHandler)
+	ep.handleFunc("/address", scopeRead, ep.addressHandler)
+	ep.handleFunc("/accounts/", scopeRead, ep.accountsHandler)
+	ep.handleFunc("/demurrage", scopeRead, ep.demurrageHandler)
+	ep.handleFunc("/supply", scopeRead, ep.supplyHandler)
+	ep.handleFunc("/richlist", scopeRead, ep.richListHandler)
+	ep.handleFunc("/pending", scopeRead, ep.pendingHandler)
+	ep.handleFunc("/graphql", scopeRead, ep.graphqlHandler)
+	ep.handleFunc("/webhooks", scopeAdmin, ep.webhooksHandler)
+	ep.handleFunc("/metrics", scopeAdmin, ep.metricsHandler)
+	ep.handleFunc("/healthz", scopeNone, ep.healthzHandler)
+	ep.handleFunc("/readyz", scopeNone, ep.readyzHandler)
+	ep.handleFunc("/openapi.json", scopeRead, ep.openAPIHandler)
+
+	// The hub runs until Stop so that the endpoint can be served by any
+	// http server
+	go ep.run()
 
 	return ep
 }
 
-func enableCors(w *http.ResponseWriter) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
+// handleFunc registers the handler for the given pattern. Requests must be
+// granted scope to reach the handler.
+func (ep *Endpoint) handleFunc(pattern string, scope string, handler http.HandlerFunc) {
+	ep.mux.HandleFunc(pattern, instrument(pattern, ep.access.wrap(scope, handler)))
+}
+
+// ServeHTTP implements http.Handler by dispatching requests to the routes of
+// the endpoint
+func (ep *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ep.mux.ServeHTTP(w, r)
 }
 
 func (ep *Endpoint) logsHandler(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
-	http.ServeFile(w, r, logFile)
+	if *logFile == "" {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, *logFile)
 }
 
 func (ep *Endpoint) chainHandler(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
 	state := struct {
 		Blockchain []*types.Block `json:"blockchain"`
 	}{}
@@ -106,7 +197,6 @@ func (ep *Endpoint) chainHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ep *Endpoint) stateHandler(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
 	state := struct {
 		Blockchain   []*types.Block
 		Transactions []*types.Transaction
@@ -141,6 +231,22 @@ func (ep *Endpoint) balanceHandler(w http.ResponseWriter, r *http.Request) {
 	addr.FromBytes(bytes)
 	balance := ep.Currency.GetBalance(addr)
 
+	// An optional block number queries the balance held at the end of that
+	// block instead of the current one.
+	if blocks, ok := r.URL.Query()["block"]; ok && len(blocks[0]) > 0 {
+		number, err := strconv.ParseUint(blocks[0], 10, 64)
+		if err != nil {
+			http.Error(w, "invalid block number", http.StatusBadRequest)
+			return
+		}
+		st, err := ep.Currency.BlockChain().StateAt(number)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		balance = st.GetBalance(addr)
+	}
+
 	balanceJSON := struct {
 		Balance uint64 `json:"balance"`
 	}{}
@@ -152,7 +258,6 @@ func (ep *Endpoint) balanceHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ep *Endpoint) helloHandler(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
 	res := json.NewEncoder(w)
 	res.Encode("Hello world")
 }
@@ -165,45 +270,140 @@ func (ep *Endpoint) SetNetworkMapGetter(networkMapGetter func() map[ibft.Address
 func (ep *Endpoint) run() {
 	for {
 		select {
+		case <-ep.quit:
+			for client := range ep.clients {
+				ep.dropClient(client)
+			}
+			return
 		case client := <-ep.register:
 			ep.clients[client] = true
+			websocketClients.Inc()
 		case client := <-ep.unregister:
 			if _, ok := ep.clients[client]; ok {
-				delete(ep.clients, client)
-				close(client.send)
+				ep.dropClient(client)
 			}
 		case message := <-ep.broadcast:
 			for client := range ep.clients {
 				select {
 				case client.send <- message:
 				default:
-					close(client.send)
-					delete(ep.clients, client)
+					ep.dropClient(client)
 				}
 			}
+		case n := <-ep.notifications:
+			for client := range ep.clients {
+				ep.deliver(client, n)
+			}
+		case r := <-ep.replies:
+			if !ep.clients[r.client] {
+				break
+			}
+			select {
+			case r.client.send <- r.msg:
+			default:
+				ep.dropClient(r.client)
+			}
+		}
+	}
+}
+
+// deliver sends a notification to each matching subscription of a client.
+// Like broadcasts, clients that are not keeping up are dropped.
+// Note, this function must only be called from the hub goroutine.
+func (ep *Endpoint) deliver(client *Client, n *notification) {
+	for _, sub := range client.subs.match(n) {
+		for _, result := range n.results(sub) {
+			select {
+			case client.send <- message{
+				Type: "subscription",
+				Data: subscriptionResult{Subscription: sub.ID, Result: result},
+			}:
+			default:
+				ep.dropClient(client)
+				return
+			}
 		}
 	}
 }
 
-// Start starts the endpoint
+// dropClient closes the send channel of a client and forgets it.
+// Note, this function must only be called from the hub goroutine.
+func (ep *Endpoint) dropClient(client *Client) {
+	close(client.send)
+	delete(ep.clients, client)
+	websocketClients.Dec()
+}
+
+// Start starts the endpoint. It returns once the endpoint is stopped.
 func (ep *Endpoint) Start(addr string) {
-	go ep.run()
+	if ep.webhooks != nil {
+		ep.webhooksWg.Add(1)
+		go func() {
+			defer ep.webhooksWg.Done()
+			ep.webhooks.run(ep.quit)
+		}()
+	}
 
-	err := http.ListenAndServe(addr, nil)
-	if err != nil {
+	server := &http.Server{Addr: addr, Handler: ep.mux}
+	ep.serverMu.Lock()
+	ep.server = server
+	ep.serverMu.Unlock()
+
+	var err error
+	if !tlsEnabled() {
+		err = server.ListenAndServe()
+	} else if server.TLSConfig, err = serverTLSConfig(); err == nil {
+		err = server.ListenAndServeTLS(*tlsCertFile, *tlsKeyFile)
+	}
+	if err != nil && err != http.ErrServerClosed {
 		ep.debug.Errorf("ListenAndServe: %v", err)
 	}
 }
 
-func (ep *Endpoint) handleMsg(msg *message, cli *Client) {
+// Stop gracefully shuts the endpoint down. In-flight requests are drained,
+// websocket clients are disconnected and the webhook worker finishes its
+// deliveries before it returns, unless ctx expires first.
+func (ep *Endpoint) Stop(ctx context.Context) error {
+	ep.serverMu.Lock()
+	server := ep.server
+	ep.serverMu.Unlock()
+
+	var err error
+	if server != nil {
+		err = server.Shutdown(ctx)
+	}
+	ep.quitOnce.Do(func() { close(ep.quit) })
+
+	done := make(chan struct{})
+	go func() {
+		ep.clientsWg.Wait()
+		ep.webhooksWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// The webhook worker and its deliveries have returned, nothing writes
+	// to the webhook database anymore
+	if ep.webhooks != nil {
+		ep.webhooks.db.Close()
+	}
+	return err
+}
+
+func (ep *Endpoint) handleMsg(msg *request, cli *Client) {
 	ep.debug.Infof("Received client req: %s", msg.Type)
 	switch msg.Type {
+	case "subscribe":
+		ep.handleSubscribe(msg.Data, cli)
+	case "unsubscribe":
+		ep.handleUnsubscribe(msg.Data, cli)
 	case "network-state":
 		if ep.networkMapGetter == nil {
-			cli.send <- message{
-				Type: "error",
-				Data: "Network getter is not configured on this server: nil",
-			}
+			cli.sendError("Network getter is not configured on this server: nil")
 			return
 		}
 		network := ep.networkMapGetter()
@@ -214,10 +414,10 @@ func (ep *Endpoint) handleMsg(msg *message, cli *Client) {
 			netmap[k.String()] = v
 		}
 
-		cli.send <- message{
+		cli.reply(message{
 			Type: "network-state",
 			Data: netmap,
-		}
+		})
 
 	}
 }
@@ -260,7 +460,10 @@ func (ep *Endpoint) publishEvent(e interface{}, eType string) {
 	}
 
 	msg.DataType = reflect.TypeOf(msg.Data).String()
-	ep.broadcast <- msg
+	select {
+	case ep.broadcast <- msg:
+	case <-ep.quit:
+	}
 }
 
 // EventProxy returns a directional channel proxy that forwards core.Event.
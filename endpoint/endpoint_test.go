@@ -0,0 +1,93 @@
+package endpoint
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func init() {
+	*webhookDataPath = ""
+	*logFile = ""
+}
+
+func freeAddr() string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func waitForServer(addr string) {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	panic("endpoint did not start on " + addr)
+}
+
+func TestEndpointsCoexistAndStop(t *testing.T) {
+	addrs := []string{freeAddr(), freeAddr()}
+	eps := []*Endpoint{New(), New()}
+	stopped := make(chan struct{}, len(eps))
+	for i, ep := range eps {
+		go func(ep *Endpoint, addr string) {
+			ep.Start(addr)
+			stopped <- struct{}{}
+		}(ep, addrs[i])
+		waitForServer(addrs[i])
+	}
+
+	for _, addr := range addrs {
+		resp, err := http.Get("http://" + addr + "/hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /hello on %s: got %d", addr, resp.StatusCode)
+		}
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addrs[0]+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := eps[0].Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+
+	// The websocket client is disconnected once the connected message is read
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	// The other endpoint is still serving
+	resp, err := http.Get("http://" + addrs[1] + "/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	eps[1].Stop(ctx)
+}
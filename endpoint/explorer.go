@@ -0,0 +1,482 @@
+package endpoint
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/rawdb"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// logKinds names the kinds of receipt logs
+var logKinds = map[uint8]string{
+	types.LogMint:   "mint",
+	types.LogBurn:   "burn",
+	types.LogOutput: "output",
+}
+
+// blockView is the JSON representation of a block served to the explorer
+type blockView struct {
+	Number       uint64    `json:"number"`
+	Hash         string    `json:"hash"`
+	ParentHash   string    `json:"parentHash"`
+	Time         uint64    `json:"timestamp"`
+	TxCount      int       `json:"txCount"`
+	Transactions []*txView `json:"transactions,omitempty"`
+}
+
+// txView is the JSON representation of a transaction served to the explorer.
+// Block information and status are only set once the transaction is
+// committed.
+type txView struct {
+	Hash        string        `json:"hash"`
+	Type        uint8         `json:"type"`
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Amount      string        `json:"amount"`
+	Outputs     []*outputView `json:"outputs,omitempty"`
+	Reference   string        `json:"reference,omitempty"`
+	Nonce       uint64        `json:"nonce,omitempty"`
+	BlockNumber *uint64       `json:"blockNumber,omitempty"`
+	BlockHash   string        `json:"blockHash,omitempty"`
+	Status      *uint64       `json:"status,omitempty"`
+	Pending     bool          `json:"pending,omitempty"`
+}
+
+type outputView struct {
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+type receiptView struct {
+	TxHash      string            `json:"transactionHash"`
+	Status      uint64            `json:"status"`
+	BlockHash   string            `json:"blockHash"`
+	BlockNumber uint64            `json:"blockNumber"`
+	Index       uint64            `json:"index"`
+	Logs        []*receiptLogView `json:"logs,omitempty"`
+}
+
+type receiptLogView struct {
+	Kind    string `json:"kind"`
+	Account string `json:"account"`
+	Amount  string `json:"amount"`
+}
+
+type supplyView struct {
+	BlockNumber     uint64 `json:"blockNumber"`
+	TotalSupply     string `json:"totalSupply"`
+	Minted          string `json:"minted"`
+	Burned          string `json:"burned"`
+	DemurrageBurned string `json:"demurrageBurned"`
+	Accounts        uint64 `json:"accounts"`
+	Cap             string `json:"cap,omitempty"`
+}
+
+type holderView struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+}
+
+type addressView struct {
+	Address      string    `json:"address"`
+	Balance      string    `json:"balance"`
+	Transactions []*txView `json:"transactions"`
+}
+
+// chargeView is the JSON view of the demurrage charged to an account
+type chargeView struct {
+	Block   uint64 `json:"block"`
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+	Share   string `json:"share"`
+}
+
+func parseAddress(s string) (ibft.Address, error) {
+	addr := ibft.Address{}
+	bytes, err := hex.DecodeString(s)
+	if err != nil || len(bytes) != len(addr) {
+		return addr, errors.New("invalid address: " + s)
+	}
+	addr.FromBytes(bytes)
+	return addr, nil
+}
+
+func parseHash(s string) (ibft.Hash, error) {
+	bytes, err := hex.DecodeString(s)
+	if err != nil || len(bytes) != len(ibft.Hash{}) {
+		return ibft.Hash{}, errors.New("invalid hash: " + s)
+	}
+	return ibft.BytesToHash(bytes), nil
+}
+
+// intParam returns the integer value of a query parameter, or def when it is
+// missing
+func intParam(r *http.Request, name string, def uint64) (uint64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid " + name + ": " + v)
+	}
+	return n, nil
+}
+
+func pageSize(r *http.Request) (int, error) {
+	n, err := intParam(r, "count", defaultPageSize)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxPageSize {
+		n = maxPageSize
+	}
+	return int(n), nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTxView(tx *types.Transaction) *txView {
+	view := &txView{
+		Hash:      hex.EncodeToString(tx.Hash().Bytes()),
+		Type:      tx.Type,
+		From:      hex.EncodeToString(tx.From.Bytes()),
+		To:        hex.EncodeToString(tx.To.Bytes()),
+		Amount:    tx.Amount.String(),
+		Reference: hex.EncodeToString(tx.Reference),
+		Nonce:     tx.Nonce,
+	}
+	for _, output := range tx.Outputs {
+		view.Outputs = append(view.Outputs, &outputView{
+			To:     hex.EncodeToString(output.To.Bytes()),
+			Amount: output.Amount.String(),
+		})
+	}
+	return view
+}
+
+// newCommittedTxView returns the view of the index-th transaction of a block
+func newCommittedTxView(block *types.Block, receipts types.Receipts, index int) *txView {
+	view := newTxView(block.Transactions[index])
+	number := block.Number().Uint64()
+	view.BlockNumber = &number
+	view.BlockHash = hex.EncodeToString(block.Hash().Bytes())
+	if index < len(receipts) && receipts[index] != nil {
+		status := receipts[index].Status
+		view.Status = &status
+	}
+	return view
+}
+
+func newBlockView(block *types.Block) *blockView {
+	return &blockView{
+		Number:     block.Number().Uint64(),
+		Hash:       hex.EncodeToString(block.Hash().Bytes()),
+		ParentHash: hex.EncodeToString(block.ParentHash().Bytes()),
+		Time:       block.Header.Time.Uint64(),
+		TxCount:    len(block.Transactions),
+	}
+}
+
+// blocksHandler lists the latest blocks, or the ones preceding ?before=
+func (ep *Endpoint) blocksHandler(w http.ResponseWriter, r *http.Request) {
+	bc := ep.Currency.BlockChain()
+	before, err := intParam(r, "before", bc.CurrentBlock().Number().Uint64()+1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, err := pageSize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	blocks := []*blockView{}
+	for n := before; n > 0 && len(blocks) < count; n-- {
+		block := bc.GetBlockByNumber(n - 1)
+		if block == nil {
+			continue
+		}
+		blocks = append(blocks, newBlockView(block))
+	}
+	writeJSON(w, blocks)
+}
+
+// blockHandler returns a block, by ?number= or ?hash=, with its transactions
+// and their status
+func (ep *Endpoint) blockHandler(w http.ResponseWriter, r *http.Request) {
+	bc := ep.Currency.BlockChain()
+	var block *types.Block
+	if h := r.URL.Query().Get("hash"); h != "" {
+		hash, err := parseHash(h)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		block = bc.GetBlockByHash(hash)
+	} else {
+		number, err := intParam(r, "number", bc.CurrentBlock().Number().Uint64())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		block = bc.GetBlockByNumber(number)
+	}
+	if block == nil {
+		http.Error(w, "block not found", http.StatusNotFound)
+		return
+	}
+
+	view := newBlockView(block)
+	receipts := bc.GetReceiptsByHash(block.Hash())
+	view.Transactions = []*txView{}
+	for i := range block.Transactions {
+		view.Transactions = append(view.Transactions, newCommittedTxView(block, receipts, i))
+	}
+	writeJSON(w, view)
+}
+
+// txHandler returns a committed or pending transaction by ?hash=
+func (ep *Endpoint) txHandler(w http.ResponseWriter, r *http.Request) {
+	hash, err := parseHash(r.URL.Query().Get("hash"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bc := ep.Currency.BlockChain()
+	if tx, blockHash, number, index := bc.GetTransaction(hash); tx != nil {
+		block := bc.GetBlock(blockHash, number)
+		writeJSON(w, newCommittedTxView(block, bc.GetReceiptsByHash(blockHash), int(index)))
+		return
+	}
+	for _, tx := range ep.Currency.PendingTransactions() {
+		if tx.Hash() == hash {
+			view := newTxView(tx)
+			view.Pending = true
+			writeJSON(w, view)
+			return
+		}
+	}
+	http.Error(w, "transaction not found", http.StatusNotFound)
+}
+
+// receiptHandler returns the receipt of a committed transaction by ?hash=
+func (ep *Endpoint) receiptHandler(w http.ResponseWriter, r *http.Request) {
+	hash, err := parseHash(r.URL.Query().Get("hash"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bc := ep.Currency.BlockChain()
+	tx, blockHash, number, index := bc.GetTransaction(hash)
+	receipt := bc.GetReceipt(hash)
+	if tx == nil || receipt == nil {
+		http.Error(w, "receipt not found", http.StatusNotFound)
+		return
+	}
+	view := &receiptView{
+		TxHash:      hex.EncodeToString(receipt.TxHash.Bytes()),
+		Status:      receipt.Status,
+		BlockHash:   hex.EncodeToString(blockHash.Bytes()),
+		BlockNumber: number,
+		Index:       index,
+	}
+	for _, l := range receipt.Logs {
+		view.Logs = append(view.Logs, &receiptLogView{
+			Kind:    logKinds[l.Kind],
+			Account: hex.EncodeToString(l.Account.Bytes()),
+			Amount:  l.Amount.String(),
+		})
+	}
+	writeJSON(w, view)
+}
+
+// supplyHandler returns the accounting of the money supply at the head block,
+// or at ?block=
+func (ep *Endpoint) supplyHandler(w http.ResponseWriter, r *http.Request) {
+	bc := ep.Currency.BlockChain()
+	number, err := intParam(r, "block", bc.CurrentBlock().Number().Uint64())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	st, err := bc.StateAt(number)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	stats := st.Stats()
+	view := &supplyView{
+		BlockNumber:     number,
+		TotalSupply:     stats.Supply.String(),
+		Minted:          stats.Minted.String(),
+		Burned:          stats.Burned.String(),
+		DemurrageBurned: stats.DemurrageBurned.String(),
+		Accounts:        stats.Accounts,
+	}
+	if cap := bc.Config().Issuance.CapAt(number); cap != nil {
+		view.Cap = cap.String()
+	}
+	writeJSON(w, view)
+}
+
+// richListHandler lists the ?count= accounts holding the most money
+func (ep *Endpoint) richListHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := pageSize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	holders := []*holderView{}
+	for _, account := range ep.Currency.BlockChain().State().TopHolders(count) {
+		holders = append(holders, &holderView{
+			Address: hex.EncodeToString(account.Address.Bytes()),
+			Balance: account.Balance.String(),
+		})
+	}
+	writeJSON(w, holders)
+}
+
+// addressHandler returns the balance of ?account= and its latest transactions
+func (ep *Endpoint) addressHandler(w http.ResponseWriter, r *http.Request) {
+	addr, err := parseAddress(r.URL.Query().Get("account"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, err := intParam(r, "offset", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, err := pageSize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bc := ep.Currency.BlockChain()
+	view := &addressView{
+		Address:      hex.EncodeToString(addr.Bytes()),
+		Balance:      ep.Currency.GetBalance(addr).String(),
+		Transactions: []*txView{},
+	}
+	for _, entry := range bc.GetAddressTransactions(addr, int(offset), count) {
+		block := bc.GetBlock(entry.BlockHash, entry.BlockIndex)
+		if block == nil || entry.Index >= uint64(len(block.Transactions)) {
+			continue
+		}
+		view.Transactions = append(view.Transactions, newCommittedTxView(block, bc.GetReceiptsByHash(entry.BlockHash), int(entry.Index)))
+	}
+	writeJSON(w, view)
+}
+
+// accountsHandler serves /accounts/{address}/transactions, the latest
+// transactions of an account, or only its payments carrying ?reference= when
+// given
+func (ep *Endpoint) accountsHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/accounts/"), "/")
+	if len(parts) != 2 || parts[1] != "transactions" {
+		http.NotFound(w, r)
+		return
+	}
+	addr, err := parseAddress(parts[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reference, err := hex.DecodeString(r.URL.Query().Get("reference"))
+	if err != nil || len(reference) > types.MaxReferenceLength {
+		http.Error(w, "invalid reference", http.StatusBadRequest)
+		return
+	}
+	offset, err := intParam(r, "offset", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, err := pageSize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bc := ep.Currency.BlockChain()
+	var entries []*rawdb.TxLookupEntry
+	if len(reference) > 0 {
+		entries = bc.GetReferenceTransactions(addr, reference, int(offset), count)
+	} else {
+		entries = bc.GetAddressTransactions(addr, int(offset), count)
+	}
+	txs := []*txView{}
+	for _, entry := range entries {
+		block := bc.GetBlock(entry.BlockHash, entry.BlockIndex)
+		if block == nil || entry.Index >= uint64(len(block.Transactions)) {
+			continue
+		}
+		txs = append(txs, newCommittedTxView(block, bc.GetReceiptsByHash(entry.BlockHash), int(entry.Index)))
+	}
+	writeJSON(w, txs)
+}
+
+// demurrageHandler lists the latest demurrage charged to ?account=
+func (ep *Endpoint) demurrageHandler(w http.ResponseWriter, r *http.Request) {
+	addr, err := parseAddress(r.URL.Query().Get("account"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, err := intParam(r, "offset", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, err := pageSize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	charges := []*chargeView{}
+	for _, charge := range ep.Currency.BlockChain().GetAccountCharges(addr, int(offset), count) {
+		charges = append(charges, &chargeView{
+			Block:   charge.Block,
+			Address: hex.EncodeToString(charge.Address.Bytes()),
+			Amount:  charge.Amount.String(),
+			Share:   charge.Share.String(),
+		})
+	}
+	writeJSON(w, charges)
+}
+
+// pendingHandler lists the transactions waiting to be included in a block
+func (ep *Endpoint) pendingHandler(w http.ResponseWriter, r *http.Request) {
+	txs := []*txView{}
+	for _, tx := range ep.Currency.PendingTransactions() {
+		view := newTxView(tx)
+		view.Pending = true
+		txs = append(txs, view)
+	}
+	writeJSON(w, txs)
+}
+
+func (ep *Endpoint) explorerHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(explorerPage))
+}
@@ -0,0 +1,160 @@
+package endpoint
+
+// explorerPage is the single page block explorer served at /explorer. It
+// reads the chain through the JSON routes of the endpoint and follows new
+// blocks and the network map through the websocket.
+const explorerPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Slash explorer</title>
+<style>
+  body { font-family: sans-serif; margin: 0; color: #222; }
+  header { background: #1d2b3a; color: #fff; padding: .8em 1.5em; }
+  header a { color: #fff; margin-right: 1.5em; text-decoration: none; }
+  main { padding: 1em 1.5em; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { text-align: left; padding: .3em .6em; border-bottom: 1px solid #ddd; }
+  td.hash { font-family: monospace; }
+  .ok { color: #217a2b; } .failed { color: #b3261e; } .pending { color: #8a6d00; }
+  form { display: inline; float: right; }
+  input { width: 28em; }
+</style>
+</head>
+<body>
+<header>
+  <a href="#/">Blocks</a><a href="#/pending">Pending</a><a href="#/network">Network</a>
+  <form id="search"><input id="query" placeholder="block number, tx hash or address"></form>
+</header>
+<main id="view"></main>
+<script>
+(function () {
+  var token = new URLSearchParams(location.search).get("access_token");
+  var view = document.getElementById("view");
+  var network = {};
+
+  function api(path) {
+    if (token) {
+      path += (path.indexOf("?") < 0 ? "?" : "&") + "access_token=" + encodeURIComponent(token);
+    }
+    return fetch(path).then(function (res) {
+      if (!res.ok) { return res.text().then(function (t) { throw new Error(t); }); }
+      return res.json();
+    });
+  }
+
+  function esc(s) {
+    return String(s).replace(/[&<>"]/g, function (c) {
+      return { "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;" }[c];
+    });
+  }
+  function link(route, text) { return '<a href="#/' + route + '">' + esc(text) + "</a>"; }
+  function status(tx) {
+    if (tx.pending) { return '<span class="pending">pending</span>'; }
+    return tx.status === 1 ? '<span class="ok">success</span>' : '<span class="failed">failed</span>';
+  }
+  function date(ts) { return new Date(ts * 1000).toLocaleString(); }
+  function table(headers, rows) {
+    return "<table><tr><th>" + headers.join("</th><th>") + "</th></tr>" +
+      rows.map(function (r) { return "<tr><td>" + r.join("</td><td>") + "</td></tr>"; }).join("") + "</table>";
+  }
+  function txRows(txs) {
+    return txs.map(function (tx) {
+      return [
+        '<span class="hash">' + link("tx/" + tx.hash, tx.hash.slice(0, 16) + "…") + "</span>",
+        tx.blockNumber !== undefined ? link("block/" + tx.blockNumber, tx.blockNumber) : "",
+        link("address/" + tx.from, tx.from), link("address/" + tx.to, tx.to),
+        esc(tx.amount), status(tx)
+      ];
+    });
+  }
+  var txHeaders = ["Hash", "Block", "From", "To", "Amount", "Status"];
+
+  var routes = {
+    "": function () {
+      return api("/blocks").then(function (blocks) {
+        return "<h2>Latest blocks</h2>" + table(["Number", "Hash", "Time", "Transactions"],
+          blocks.map(function (b) {
+            return [link("block/" + b.number, b.number), '<span class="hash">' + esc(b.hash) + "</span>", date(b.timestamp), b.txCount];
+          }));
+      });
+    },
+    block: function (n) {
+      return api("/block?number=" + encodeURIComponent(n)).then(function (b) {
+        return "<h2>Block #" + b.number + "</h2>" + table(["Field", "Value"], [
+          ["Hash", '<span class="hash">' + esc(b.hash) + "</span>"],
+          ["Parent", b.number > 0 ? link("block/" + (b.number - 1), b.parentHash) : esc(b.parentHash)],
+          ["Time", date(b.timestamp)]
+        ]) + "<h3>Transactions</h3>" + table(txHeaders, txRows(b.transactions || []));
+      });
+    },
+    tx: function (h) {
+      return api("/tx?hash=" + encodeURIComponent(h)).then(function (tx) {
+        return "<h2>Transaction</h2>" + table(["Field", "Value"], [
+          ["Hash", '<span class="hash">' + esc(tx.hash) + "</span>"],
+          ["Status", status(tx)],
+          ["Block", tx.blockNumber !== undefined ? link("block/" + tx.blockNumber, tx.blockNumber) : ""],
+          ["From", link("address/" + tx.from, tx.from)],
+          ["To", link("address/" + tx.to, tx.to)],
+          ["Amount", esc(tx.amount)]
+        ]);
+      });
+    },
+    address: function (a) {
+      return api("/address?account=" + encodeURIComponent(a)).then(function (acc) {
+        return "<h2>Address " + esc(acc.address) + "</h2><p>Balance: <b>" + esc(acc.balance) + "</b></p>" +
+          "<h3>History</h3>" + table(txHeaders, txRows(acc.transactions));
+      });
+    },
+    pending: function () {
+      return api("/pending").then(function (txs) {
+        return "<h2>Pending transactions</h2>" + table(txHeaders, txRows(txs));
+      });
+    },
+    network: function () {
+      return Promise.resolve("<h2>Validators</h2>" + table(["Address", "Remote"],
+        Object.keys(network).map(function (addr) { return [esc(addr), esc(network[addr])]; })));
+    }
+  };
+
+  function render() {
+    var parts = location.hash.replace(/^#\/?/, "").split("/");
+    var route = routes[parts[0]] || routes[""];
+    route(parts[1]).then(function (html) { view.innerHTML = html; }, function (err) {
+      view.innerHTML = '<p class="failed">' + esc(err.message) + "</p>";
+    });
+  }
+
+  document.getElementById("search").addEventListener("submit", function (e) {
+    e.preventDefault();
+    var q = document.getElementById("query").value.trim();
+    if (/^[0-9]+$/.test(q)) { location.hash = "#/block/" + q; }
+    else if (q.length === 64) { location.hash = "#/tx/" + q; }
+    else { location.hash = "#/address/" + q; }
+  });
+  window.addEventListener("hashchange", render);
+  render();
+
+  var wsURL = (location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws" +
+    (token ? "?access_token=" + encodeURIComponent(token) : "");
+  var ws = new WebSocket(wsURL);
+  ws.onopen = function () {
+    ws.send(JSON.stringify({ type: "subscribe", data: { topic: "newHeads" } }));
+    ws.send(JSON.stringify({ type: "network-state" }));
+    setInterval(function () { ws.send(JSON.stringify({ type: "network-state" })); }, 10000);
+  };
+  ws.onmessage = function (e) {
+    var msg = JSON.parse(e.data);
+    if (msg.type === "network-state") {
+      network = msg.data;
+      if (location.hash === "#/network") { render(); }
+    } else if (msg.type === "subscription") {
+      var route = location.hash.replace(/^#\/?/, "");
+      if (route === "" || route === "pending") { render(); }
+    }
+  };
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,128 @@
+package endpoint
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+func getJSON(t *testing.T, ep *Endpoint, path string, v interface{}) {
+	rec := httptest.NewRecorder()
+	ep.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: got status %d: %s", path, rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+func TestExplorer(t *testing.T) {
+	alice, bob := ibft.Address{1}, ibft.Address{2}
+	pending := types.NewTransaction(bob, alice, big.NewInt(5))
+	dir, err := ioutil.TempDir("", "explorer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	genesis := blockchain.DefaultGenesis()
+	genesis.Alloc = map[ibft.Address]*big.Int{alice: big.NewInt(100)}
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	cur := &testCurrency{bc: bc, pending: []*types.Transaction{pending}}
+
+	// Alice pays bob twice. Bob's payment back exceeds his balance and fails.
+	parent := cur.bc.CurrentBlock()
+	blocks := []*types.Block{}
+	for i, txs := range []types.Transactions{
+		{types.NewTransaction(alice, bob, big.NewInt(10))},
+		{types.NewTransaction(alice, bob, big.NewInt(30)), types.NewTransaction(bob, alice, big.NewInt(1000))},
+	} {
+		block := types.NewBlock(&types.Header{
+			Number:     big.NewInt(int64(i + 1)),
+			ParentHash: parent.Hash(),
+			Time:       big.NewInt(int64(i + 1)),
+		}, txs)
+		blocks = append(blocks, block)
+		parent = block
+	}
+	if err := cur.bc.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	ep := New()
+	ep.Currency = cur
+
+	rec := httptest.NewRecorder()
+	ep.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/explorer", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(rec.Body.String(), "<title>Slash explorer</title>") {
+		t.Fatalf("unexpected explorer page: %d %v", rec.Code, rec.Header())
+	}
+
+	latest := []*blockView{}
+	getJSON(t, ep, "/blocks", &latest)
+	if len(latest) != 3 || latest[0].Number != 2 || latest[0].TxCount != 2 || latest[2].Number != 0 {
+		t.Fatalf("unexpected latest blocks: %+v", latest)
+	}
+	getJSON(t, ep, "/blocks?before=2&count=1", &latest)
+	if len(latest) != 1 || latest[0].Number != 1 {
+		t.Fatalf("unexpected blocks before #2: %+v", latest)
+	}
+
+	block := &blockView{}
+	getJSON(t, ep, "/block?hash="+latest[0].Hash, block)
+	if block.Number != 1 || len(block.Transactions) != 1 {
+		t.Fatalf("unexpected block #1: %+v", block)
+	}
+	getJSON(t, ep, "/block", block)
+	if block.Number != 2 || len(block.Transactions) != 2 {
+		t.Fatalf("unexpected head block: %+v", block)
+	}
+	for i, want := range []uint64{types.ReceiptStatusSuccessful, types.ReceiptStatusFailed} {
+		if status := block.Transactions[i].Status; status == nil || *status != want {
+			t.Fatalf("transaction %d of the head block has status %v, want %d", i, status, want)
+		}
+	}
+
+	tx := &txView{}
+	getJSON(t, ep, "/tx?hash="+block.Transactions[1].Hash, tx)
+	if tx.BlockNumber == nil || *tx.BlockNumber != 2 || tx.Amount != "1000" || tx.Pending {
+		t.Fatalf("unexpected committed transaction: %+v", tx)
+	}
+	tx = &txView{}
+	getJSON(t, ep, "/tx?hash="+hex.EncodeToString(pending.Hash().Bytes()), tx)
+	if !tx.Pending || tx.BlockNumber != nil || tx.Amount != "5" {
+		t.Fatalf("unexpected pending transaction: %+v", tx)
+	}
+	rec = httptest.NewRecorder()
+	ep.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tx?hash="+hex.EncodeToString(make([]byte, 32)), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("got status %d for an unknown transaction", rec.Code)
+	}
+
+	address := &addressView{}
+	getJSON(t, ep, "/address?account="+hex.EncodeToString(alice.Bytes()), address)
+	// The genesis allocation of alice is listed with her three transfers
+	if address.Balance != "60" || len(address.Transactions) != 4 {
+		t.Fatalf("unexpected address page: %+v", address)
+	}
+
+	pool := []*txView{}
+	getJSON(t, ep, "/pending", &pool)
+	if len(pool) != 1 || !pool[0].Pending || pool[0].Hash != hex.EncodeToString(pending.Hash().Bytes()) {
+		t.Fatalf("unexpected pending pool: %+v", pool)
+	}
+}
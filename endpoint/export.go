@@ -0,0 +1,77 @@
+package endpoint
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// flushWriter flushes every write so that exported blocks are sent to the
+// client as they are encoded instead of being buffered
+type flushWriter struct {
+	w io.Writer
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+// exportHandler streams the RLP encoded blocks from ?from= to ?to= included,
+// the head by default. The head of the chain is announced in the X-Chain-Head
+// header.
+func (ep *Endpoint) exportHandler(w http.ResponseWriter, r *http.Request) {
+	bc := ep.Currency.BlockChain()
+	head := bc.CurrentBlock().Number().Uint64()
+	from, err := intParam(r, "from", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := intParam(r, "to", head)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to > head {
+		to = head
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Chain-Head", strconv.FormatUint(head, 10))
+	if from > to {
+		return
+	}
+	if err := bc.ExportN(flushWriter{w}, from, to); err != nil {
+		// The response has started, aborting it is the only way to let the
+		// client know the stream is incomplete
+		ep.debug.Warningf("export failed: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// importHandler inserts the RLP encoded blocks of the request body, as served
+// by /export, into the chain. It is refused while the consensus runs, as the
+// committed blocks would be processed on the state the import modifies.
+func (ep *Endpoint) importHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ep.Currency.Status().CoreRunning {
+		http.Error(w, "the consensus is running, import blocks while the node syncs or waits for the validators", http.StatusConflict)
+		return
+	}
+	imported, err := ep.Currency.BlockChain().Import(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, struct {
+		Imported int    `json:"imported"`
+		Head     uint64 `json:"head"`
+	}{imported, ep.Currency.BlockChain().CurrentBlock().Number().Uint64()})
+}
@@ -0,0 +1,79 @@
+package endpoint
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+func TestExportImport(t *testing.T) {
+	src, cleanup := newTestCurrency()
+	defer cleanup()
+	blocks := []*types.Block{}
+	parent := src.bc.CurrentBlock()
+	for i := 1; i <= 600; i++ {
+		block := types.NewBlock(&types.Header{
+			Number:     big.NewInt(int64(i)),
+			ParentHash: parent.Hash(),
+			Time:       big.NewInt(int64(i)),
+		}, types.Transactions{})
+		blocks = append(blocks, block)
+		parent = block
+	}
+	if err := src.bc.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	srcEp := New()
+	srcEp.Currency = src
+
+	dst, cleanup := newTestCurrency()
+	defer cleanup()
+	if err := dst.bc.ResetWithGenesis(src.bc.GetBlockByNumber(0)); err != nil {
+		t.Fatal(err)
+	}
+	dstEp := New()
+	dstEp.Currency = dst
+	*dstEp.access.config = AccessConfig{PublicScopes: []string{scopeAdmin}}
+
+	// Imports would race with the committed blocks while the consensus runs
+	dst.status.CoreRunning = true
+	imp := httptest.NewRecorder()
+	dstEp.ServeHTTP(imp, httptest.NewRequest(http.MethodPost, "/import", nil))
+	if imp.Code != http.StatusConflict {
+		t.Fatalf("got status %d for an import while the consensus runs", imp.Code)
+	}
+	dst.status.CoreRunning = false
+
+	// Blocks already part of the destination chain are skipped
+	for _, from := range []string{"0", "300"} {
+		export := httptest.NewRecorder()
+		srcEp.ServeHTTP(export, httptest.NewRequest(http.MethodGet, "/export?from="+from, nil))
+		if export.Code != http.StatusOK || export.Header().Get("X-Chain-Head") != "600" {
+			t.Fatalf("export from %s failed: %d %v", from, export.Code, export.Header())
+		}
+
+		if from == "300" {
+			if err := dst.bc.SetHead(400); err != nil {
+				t.Fatal(err)
+			}
+		}
+		imp := httptest.NewRecorder()
+		dstEp.ServeHTTP(imp, httptest.NewRequest(http.MethodPost, "/import", export.Body))
+		if imp.Code != http.StatusOK {
+			t.Fatalf("import from %s failed: %s", from, imp.Body.String())
+		}
+		if dst.bc.CurrentBlock().Hash() != src.bc.CurrentBlock().Hash() {
+			t.Fatalf("import from %s: got head %v, want %v", from, dst.bc.CurrentBlock().Number(), src.bc.CurrentBlock().Number())
+		}
+	}
+
+	// An empty range only announces the head
+	export := httptest.NewRecorder()
+	srcEp.ServeHTTP(export, httptest.NewRequest(http.MethodGet, "/export?from=601", nil))
+	if export.Code != http.StatusOK || export.Body.Len() != 0 || export.Header().Get("X-Chain-Head") != "600" {
+		t.Fatalf("unexpected export past the head: %d %d", export.Code, export.Body.Len())
+	}
+}
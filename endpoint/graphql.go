@@ -0,0 +1,433 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/graph-gophers/graphql-go"
+)
+
+var (
+	graphqlMaxDepth      = flag.Int("graphql-max-depth", 8, "maximum nesting depth of a graphql query")
+	graphqlMaxComplexity = flag.Int64("graphql-max-complexity", 1000, "maximum number of objects a graphql query may resolve")
+)
+
+const graphqlSchema = `
+	schema {
+		query: Query
+	}
+
+	# Long is a 64 bit unsigned integer
+	scalar Long
+
+	type Query {
+		# A block by number or hash, the current block when both are omitted
+		block(number: Long, hash: String): Block
+		# Blocks from number "from" to "to" included, at most 100 of them
+		blocks(from: Long!, to: Long): [Block!]!
+		# A committed or pending transaction
+		transaction(hash: String!): Transaction
+		account(address: String!): Account!
+		pending: Pending!
+	}
+
+	type Header {
+		number: Long!
+		hash: String!
+		parentHash: String!
+		timestamp: Long!
+	}
+
+	type Block {
+		number: Long!
+		hash: String!
+		header: Header!
+		parent: Block
+		transactionCount: Int!
+		transactions: [Transaction!]!
+	}
+
+	type Transaction {
+		hash: String!
+		from: Account!
+		to: Account!
+		amount: String!
+		# Block and index are null while the transaction is pending
+		block: Block
+		index: Int
+		receipt: Receipt
+		pending: Boolean!
+	}
+
+	type Receipt {
+		transactionHash: String!
+		status: Long!
+	}
+
+	type Account {
+		address: String!
+		# The balance at the end of the given block, the current one by default
+		balance(block: Long): String!
+		# The committed transactions of the account, newest first
+		transactions(offset: Int = 0, count: Int = 20): [Transaction!]!
+	}
+
+	type Pending {
+		count: Int!
+		transactions: [Transaction!]!
+	}
+`
+
+var errComplexity = errors.New("query complexity limit exceeded")
+
+// Long is the graphql scalar holding block numbers and timestamps
+type Long uint64
+
+// ImplementsGraphQLType implements graphql.Unmarshaler
+func (Long) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+// UnmarshalGraphQL implements graphql.Unmarshaler
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		if v < 0 {
+			return fmt.Errorf("negative Long: %d", v)
+		}
+		*l = Long(v)
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return fmt.Errorf("invalid Long: %v", v)
+		}
+		*l = Long(v)
+	case string:
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*l = Long(n)
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+	return nil
+}
+
+type complexityKey struct{}
+
+// charge consumes n units of the complexity budget of a query. Every resolved
+// object costs one unit, which bounds the work done by nested lists.
+func charge(ctx context.Context, n int) error {
+	budget, ok := ctx.Value(complexityKey{}).(*int64)
+	if !ok {
+		return nil
+	}
+	if atomic.AddInt64(budget, -int64(n)) < 0 {
+		return errComplexity
+	}
+	return nil
+}
+
+func newGraphQLSchema(ep *Endpoint) *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchema, &graphqlResolver{ep},
+		graphql.MaxDepth(*graphqlMaxDepth),
+		graphql.MaxQueryLength(16*1024),
+	)
+}
+
+// graphqlHandler executes a query given as a JSON body or a ?query= parameter
+func (ep *Endpoint) graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	params := struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}{}
+	switch r.Method {
+	case http.MethodGet:
+		params.Query = r.URL.Query().Get("query")
+		params.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &params.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&params); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	budget := *graphqlMaxComplexity
+	ctx := context.WithValue(r.Context(), complexityKey{}, &budget)
+	writeJSON(w, ep.schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+}
+
+type graphqlResolver struct {
+	ep *Endpoint
+}
+
+func (r *graphqlResolver) Block(ctx context.Context, args struct {
+	Number *Long
+	Hash   *string
+}) (*blockResolver, error) {
+	bc := r.ep.Currency.BlockChain()
+	var block *types.Block
+	switch {
+	case args.Hash != nil:
+		hash, err := parseHash(*args.Hash)
+		if err != nil {
+			return nil, err
+		}
+		block = bc.GetBlockByHash(hash)
+	case args.Number != nil:
+		block = bc.GetBlockByNumber(uint64(*args.Number))
+	default:
+		block = bc.CurrentBlock()
+	}
+	return newBlockResolver(ctx, r.ep, block)
+}
+
+func (r *graphqlResolver) Blocks(ctx context.Context, args struct {
+	From Long
+	To   *Long
+}) ([]*blockResolver, error) {
+	bc := r.ep.Currency.BlockChain()
+	to := bc.CurrentBlock().Number().Uint64()
+	if args.To != nil && uint64(*args.To) < to {
+		to = uint64(*args.To)
+	}
+	from := uint64(args.From)
+	if from <= to && to-from >= maxPageSize {
+		to = from + maxPageSize - 1
+	}
+
+	blocks := []*blockResolver{}
+	for n := from; n <= to; n++ {
+		block, err := newBlockResolver(ctx, r.ep, bc.GetBlockByNumber(n))
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
+
+func (r *graphqlResolver) Transaction(ctx context.Context, args struct{ Hash string }) (*txResolver, error) {
+	hash, err := parseHash(args.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	bc := r.ep.Currency.BlockChain()
+	if tx, blockHash, number, index := bc.GetTransaction(hash); tx != nil {
+		return &txResolver{ep: r.ep, tx: tx, block: bc.GetBlock(blockHash, number), index: int(index)}, nil
+	}
+	for _, tx := range r.ep.Currency.PendingTransactions() {
+		if tx.Hash() == hash {
+			return &txResolver{ep: r.ep, tx: tx}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *graphqlResolver) Account(ctx context.Context, args struct{ Address string }) (*accountResolver, error) {
+	addr, err := parseAddress(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	return newAccountResolver(ctx, r.ep, addr)
+}
+
+func (r *graphqlResolver) Pending(ctx context.Context) (*pendingResolver, error) {
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &pendingResolver{ep: r.ep, txs: r.ep.Currency.PendingTransactions()}, nil
+}
+
+type headerResolver struct {
+	block *types.Block
+}
+
+func (h *headerResolver) Number() Long       { return Long(h.block.Number().Uint64()) }
+func (h *headerResolver) Hash() string       { return hex.EncodeToString(h.block.Hash().Bytes()) }
+func (h *headerResolver) ParentHash() string { return hex.EncodeToString(h.block.ParentHash().Bytes()) }
+func (h *headerResolver) Timestamp() Long    { return Long(h.block.Header.Time.Uint64()) }
+
+type blockResolver struct {
+	headerResolver
+	ep *Endpoint
+}
+
+// newBlockResolver returns a resolver for block, or nil when it is unknown
+func newBlockResolver(ctx context.Context, ep *Endpoint, block *types.Block) (*blockResolver, error) {
+	if block == nil {
+		return nil, nil
+	}
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &blockResolver{headerResolver{block}, ep}, nil
+}
+
+func (b *blockResolver) Header() *headerResolver { return &b.headerResolver }
+
+func (b *blockResolver) TransactionCount() int32 { return int32(len(b.block.Transactions)) }
+
+func (b *blockResolver) Parent(ctx context.Context) (*blockResolver, error) {
+	if b.block.Number().Sign() == 0 {
+		return nil, nil
+	}
+	parent := b.ep.Currency.BlockChain().GetBlock(b.block.ParentHash(), b.block.Number().Uint64()-1)
+	return newBlockResolver(ctx, b.ep, parent)
+}
+
+func (b *blockResolver) Transactions(ctx context.Context) ([]*txResolver, error) {
+	if err := charge(ctx, len(b.block.Transactions)); err != nil {
+		return nil, err
+	}
+	txs := make([]*txResolver, len(b.block.Transactions))
+	for i, tx := range b.block.Transactions {
+		txs[i] = &txResolver{ep: b.ep, tx: tx, block: b.block, index: i}
+	}
+	return txs, nil
+}
+
+// txResolver resolves a committed transaction, or a pending one when block is
+// nil
+type txResolver struct {
+	ep    *Endpoint
+	tx    *types.Transaction
+	block *types.Block
+	index int
+}
+
+func (t *txResolver) Hash() string   { return hex.EncodeToString(t.tx.Hash().Bytes()) }
+func (t *txResolver) Amount() string { return t.tx.Amount.String() }
+func (t *txResolver) Pending() bool  { return t.block == nil }
+func (t *txResolver) From(ctx context.Context) (*accountResolver, error) {
+	return newAccountResolver(ctx, t.ep, t.tx.From)
+}
+func (t *txResolver) To(ctx context.Context) (*accountResolver, error) {
+	return newAccountResolver(ctx, t.ep, t.tx.To)
+}
+
+func (t *txResolver) Block(ctx context.Context) (*blockResolver, error) {
+	return newBlockResolver(ctx, t.ep, t.block)
+}
+
+func (t *txResolver) Index() *int32 {
+	if t.block == nil {
+		return nil
+	}
+	index := int32(t.index)
+	return &index
+}
+
+func (t *txResolver) Receipt(ctx context.Context) (*receiptResolver, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts := t.ep.Currency.BlockChain().GetReceiptsByHash(t.block.Hash())
+	if t.index >= len(receipts) || receipts[t.index] == nil {
+		return nil, nil
+	}
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &receiptResolver{receipts[t.index]}, nil
+}
+
+type receiptResolver struct {
+	receipt *types.Receipt
+}
+
+func (r *receiptResolver) TransactionHash() string {
+	return hex.EncodeToString(r.receipt.TxHash.Bytes())
+}
+func (r *receiptResolver) Status() Long { return Long(r.receipt.Status) }
+
+type accountResolver struct {
+	ep   *Endpoint
+	addr ibft.Address
+}
+
+func newAccountResolver(ctx context.Context, ep *Endpoint, addr ibft.Address) (*accountResolver, error) {
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &accountResolver{ep, addr}, nil
+}
+
+func (a *accountResolver) Address() string { return hex.EncodeToString(a.addr.Bytes()) }
+
+func (a *accountResolver) Balance(args struct{ Block *Long }) (string, error) {
+	if args.Block == nil {
+		return a.ep.Currency.GetBalance(a.addr).String(), nil
+	}
+	st, err := a.ep.Currency.BlockChain().StateAt(uint64(*args.Block))
+	if err != nil {
+		return "", err
+	}
+	return st.GetBalance(a.addr).String(), nil
+}
+
+func (a *accountResolver) Transactions(ctx context.Context, args struct {
+	Offset int32
+	Count  int32
+}) ([]*txResolver, error) {
+	if args.Offset < 0 || args.Count < 0 {
+		return nil, errors.New("offset and count must be positive")
+	}
+	if args.Count > maxPageSize {
+		args.Count = maxPageSize
+	}
+	bc := a.ep.Currency.BlockChain()
+	entries := bc.GetAddressTransactions(a.addr, int(args.Offset), int(args.Count))
+	if err := charge(ctx, len(entries)); err != nil {
+		return nil, err
+	}
+	txs := []*txResolver{}
+	for _, entry := range entries {
+		block := bc.GetBlock(entry.BlockHash, entry.BlockIndex)
+		if block == nil || entry.Index >= uint64(len(block.Transactions)) {
+			continue
+		}
+		txs = append(txs, &txResolver{ep: a.ep, tx: block.Transactions[entry.Index], block: block, index: int(entry.Index)})
+	}
+	return txs, nil
+}
+
+type pendingResolver struct {
+	ep  *Endpoint
+	txs []*types.Transaction
+}
+
+func (p *pendingResolver) Count() int32 { return int32(len(p.txs)) }
+
+func (p *pendingResolver) Transactions(ctx context.Context) ([]*txResolver, error) {
+	if err := charge(ctx, len(p.txs)); err != nil {
+		return nil, err
+	}
+	txs := make([]*txResolver, len(p.txs))
+	for i, tx := range p.txs {
+		txs[i] = &txResolver{ep: p.ep, tx: tx}
+	}
+	return txs, nil
+}
@@ -0,0 +1,123 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+type testCurrency struct {
+	bc      *blockchain.BlockChain
+	pending []*types.Transaction
+	status  NodeStatus
+}
+
+func (c *testCurrency) DecodeProposal(*ibft.EncodedProposal) (ibft.Proposal, error) { return nil, nil }
+func (c *testCurrency) BlockChain() *blockchain.BlockChain                          { return c.bc }
+func (c *testCurrency) PendingTransactions() []*types.Transaction                   { return c.pending }
+func (c *testCurrency) GetBalance(addr ibft.Address) *big.Int                       { return c.bc.State().GetBalance(addr) }
+func (c *testCurrency) SubmitTransaction(tx *types.Transaction) error {
+	c.pending = append(c.pending, types.NewTransaction(tx.From, tx.To, tx.Amount))
+	return nil
+}
+func (c *testCurrency) Status() NodeStatus { return c.status }
+
+func newTestCurrency(pending ...*types.Transaction) (*testCurrency, func()) {
+	dir, err := ioutil.TempDir("", "currency")
+	if err != nil {
+		panic(err)
+	}
+	bc, err := blockchain.New(dir)
+	if err != nil {
+		panic(err)
+	}
+	return &testCurrency{bc: bc, pending: pending}, func() { os.RemoveAll(dir) }
+}
+
+func graphqlQuery(ep *Endpoint, query string) map[string]interface{} {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	ep.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		panic("graphql request failed: " + rec.Body.String())
+	}
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestGraphQL(t *testing.T) {
+	from, to := ibft.Address{1}, ibft.Address{2}
+	txs := []*types.Transaction{}
+	for i := 0; i < 10; i++ {
+		txs = append(txs, types.NewTransaction(from, to, big.NewInt(int64(i+1))))
+	}
+	cur, cleanup := newTestCurrency(txs...)
+	defer cleanup()
+	ep := New()
+	ep.Currency = cur
+
+	result := graphqlQuery(ep, `{
+		block(number: 0) { number header { parentHash } transactionCount }
+		pending { count transactions { amount pending from { balance } } }
+	}`)
+	if errs, ok := result["errors"]; ok {
+		t.Fatal(errs)
+	}
+	data := result["data"].(map[string]interface{})
+	block := data["block"].(map[string]interface{})
+	if block["number"] != float64(0) || block["transactionCount"] != float64(0) {
+		t.Fatalf("unexpected genesis block: %v", block)
+	}
+	pending := data["pending"].(map[string]interface{})
+	if pending["count"] != float64(len(txs)) {
+		t.Fatalf("got %v pending transactions, want %d", pending["count"], len(txs))
+	}
+	first := pending["transactions"].([]interface{})[0].(map[string]interface{})
+	if first["amount"] != "1" || first["pending"] != true {
+		t.Fatalf("unexpected pending transaction: %v", first)
+	}
+}
+
+func TestGraphQLComplexity(t *testing.T) {
+	txs := []*types.Transaction{}
+	for i := 0; i < 10; i++ {
+		txs = append(txs, types.NewTransaction(ibft.Address{1}, ibft.Address{2}, big.NewInt(1)))
+	}
+	cur, cleanup := newTestCurrency(txs...)
+	defer cleanup()
+	defer func(max int64) { *graphqlMaxComplexity = max }(*graphqlMaxComplexity)
+	*graphqlMaxComplexity = 15
+	ep := New()
+	ep.Currency = cur
+
+	// The pending pool, its 10 transactions and their senders cost 21 units
+	result := graphqlQuery(ep, `{ pending { transactions { from { address } } } }`)
+	errs, ok := result["errors"].([]interface{})
+	if !ok || !strings.Contains(errs[0].(map[string]interface{})["message"].(string), errComplexity.Error()) {
+		t.Fatalf("expected a complexity error, got %v", result)
+	}
+
+	result = graphqlQuery(ep, `{ pending { transactions { amount } } }`)
+	if errs, ok := result["errors"]; ok {
+		t.Fatal(errs)
+	}
+
+	// Deep queries are rejected before execution
+	result = graphqlQuery(ep, `{ block { parent { parent { parent { parent { parent { parent { parent { parent { number } } } } } } } } } }`)
+	if _, ok := result["errors"]; !ok {
+		t.Fatalf("expected a depth error, got %v", result)
+	}
+}
@@ -0,0 +1,108 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var maxBlocksBehind = flag.Uint64("max-blocks-behind", 5, "number of blocks a node can lag behind its peers and still be ready")
+
+// NodeStatus is the consensus and synchronization state reported by the
+// currency to the health probes
+type NodeStatus struct {
+	CoreRunning               bool
+	WaitingForValSet          bool
+	WaitingForCAAuthorization bool
+	LastCommit                time.Time
+	DesyncTimeout             time.Duration
+	Head                      uint64
+	BestPeerHead              uint64
+	DBError                   error
+}
+
+type check struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+type probeResult struct {
+	Status string           `json:"status"`
+	Checks map[string]check `json:"checks"`
+}
+
+// healthChecks are the checks failing when the process must be restarted
+func healthChecks(s NodeStatus) map[string]check {
+	db := check{OK: s.DBError == nil, Detail: "writable"}
+	if s.DBError != nil {
+		db.Detail = s.DBError.Error()
+	}
+	return map[string]check{"database": db}
+}
+
+// readyChecks are the checks failing while the node should not receive
+// traffic
+func readyChecks(s NodeStatus) map[string]check {
+	checks := healthChecks(s)
+
+	if s.WaitingForCAAuthorization {
+		checks["authorization"] = check{OK: false, Detail: "waiting for CA authorization"}
+	} else {
+		checks["authorization"] = check{OK: true, Detail: "authorized"}
+	}
+
+	if s.WaitingForValSet {
+		checks["validators"] = check{OK: false, Detail: "waiting for the validator set"}
+	} else {
+		checks["validators"] = check{OK: true, Detail: "validator set received"}
+	}
+
+	if s.CoreRunning {
+		checks["core"] = check{OK: true, Detail: "running"}
+	} else {
+		checks["core"] = check{OK: false, Detail: "stopped"}
+	}
+
+	age := time.Since(s.LastCommit).Round(time.Second)
+	checks["lastCommit"] = check{
+		OK:     age < s.DesyncTimeout,
+		Detail: fmt.Sprintf("%v ago (timeout %v)", age, s.DesyncTimeout),
+	}
+
+	behind := uint64(0)
+	if s.BestPeerHead > s.Head {
+		behind = s.BestPeerHead - s.Head
+	}
+	checks["sync"] = check{
+		OK:     behind <= *maxBlocksBehind,
+		Detail: fmt.Sprintf("head #%d, %d blocks behind best peer #%d", s.Head, behind, s.BestPeerHead),
+	}
+
+	return checks
+}
+
+func writeProbe(w http.ResponseWriter, checks map[string]check) {
+	res := probeResult{Status: "ok", Checks: checks}
+	for _, c := range checks {
+		if !c.OK {
+			res.Status = "fail"
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if res.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// healthzHandler is the liveness probe
+func (ep *Endpoint) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, healthChecks(ep.Currency.Status()))
+}
+
+// readyzHandler is the readiness probe
+func (ep *Endpoint) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, readyChecks(ep.Currency.Status()))
+}
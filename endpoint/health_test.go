@@ -0,0 +1,57 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbes(t *testing.T) {
+	c, cleanup := newTestCurrency()
+	defer cleanup()
+	ep := New()
+	ep.Currency = c
+	ready := NodeStatus{
+		CoreRunning:   true,
+		LastCommit:    time.Now(),
+		DesyncTimeout: time.Minute,
+		Head:          10,
+		BestPeerHead:  12,
+	}
+
+	for _, test := range []struct {
+		update  func(*NodeStatus)
+		healthy bool
+		ready   bool
+		failing string
+	}{
+		{func(*NodeStatus) {}, true, true, ""},
+		{func(s *NodeStatus) { s.DBError = errors.New("read-only") }, false, false, "database"},
+		{func(s *NodeStatus) { s.WaitingForCAAuthorization = true }, true, false, "authorization"},
+		{func(s *NodeStatus) { s.WaitingForValSet = true }, true, false, "validators"},
+		{func(s *NodeStatus) { s.CoreRunning = false }, true, false, "core"},
+		{func(s *NodeStatus) { s.LastCommit = time.Now().Add(-2 * time.Minute) }, true, false, "lastCommit"},
+		{func(s *NodeStatus) { s.BestPeerHead = 10 + *maxBlocksBehind + 1 }, true, false, "sync"},
+	} {
+		c.status = ready
+		test.update(&c.status)
+		for _, probe := range []struct {
+			path string
+			ok   bool
+		}{{"/healthz", test.healthy}, {"/readyz", test.ready}} {
+			rec := httptest.NewRecorder()
+			ep.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, probe.path, nil))
+			res := probeResult{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			if (rec.Code == http.StatusOK) != probe.ok || (res.Status == "ok") != probe.ok {
+				t.Errorf("%s failing: got %s %d %+v", test.failing, probe.path, rec.Code, res)
+			}
+			if check, ok := res.Checks[test.failing]; !probe.ok && (!ok || check.OK) {
+				t.Errorf("%s failing: got %s checks %+v", test.failing, probe.path, res.Checks)
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package endpoint
+
+import (
+	"net/http"
+	"time"
+
+	"bitbucket.org/ventureslash/go-slash-currency/metrics"
+)
+
+var (
+	requestLatency   = metrics.NewRegisteredHistogramVec("slash_endpoint_request_duration_seconds", "Time spent serving endpoint requests per route.", "route", metrics.DefaultBuckets)
+	websocketClients = metrics.NewRegisteredGauge("slash_websocket_clients", "Number of connected websocket clients.")
+)
+
+// instrument records the latency of each request served by handler
+func instrument(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		handler(w, r)
+		requestLatency.With(route).ObserveSince(start)
+	}
+}
+
+func (ep *Endpoint) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.DefaultRegistry.Write(w)
+}
@@ -0,0 +1,561 @@
+package endpoint
+
+import "net/http"
+
+// openAPISpec describes the routes of the endpoint and the messages exchanged
+// over the /ws websocket. It is served at /openapi.json.
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Slash currency node endpoint",
+    "version": "1.0.0",
+    "description": "Chain reads, transaction submission, node synchronization and administration. Addresses and hashes are hex encoded without 0x prefix, amounts are decimal strings unless stated otherwise."
+  },
+  "components": {
+    "securitySchemes": {
+      "bearer": {"type": "http", "scheme": "bearer"},
+      "apiKey": {"type": "apiKey", "in": "header", "name": "X-API-Key"},
+      "accessToken": {"type": "apiKey", "in": "query", "name": "access_token"}
+    },
+    "parameters": {
+      "account": {"name": "account", "in": "query", "required": true, "schema": {"$ref": "#/components/schemas/Address"}},
+      "hash": {"name": "hash", "in": "query", "required": true, "schema": {"$ref": "#/components/schemas/Hash"}},
+      "count": {"name": "count", "in": "query", "schema": {"type": "integer", "minimum": 0, "maximum": 100, "default": 20}},
+      "offset": {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}}
+    },
+    "responses": {
+      "error": {"description": "Invalid request or missing resource", "content": {"text/plain": {"schema": {"type": "string"}}}},
+      "unauthorized": {"description": "Missing scope or invalid API key", "content": {"text/plain": {"schema": {"type": "string"}}}},
+      "rateLimited": {"description": "Rate limit exceeded", "content": {"text/plain": {"schema": {"type": "string"}}}}
+    },
+    "schemas": {
+      "Address": {"type": "string", "pattern": "^[0-9a-f]{40}$"},
+      "Hash": {"type": "string", "pattern": "^[0-9a-f]{64}$"},
+      "Amount": {"type": "string", "pattern": "^[0-9]+$"},
+      "Reference": {"type": "string", "pattern": "^([0-9a-f]{2}){0,64}$", "description": "hex encoded reference of a payment, such as the invoice it pays"},
+      "Balance": {
+        "type": "object",
+        "properties": {"balance": {"type": "integer", "format": "uint64"}}
+      },
+      "Block": {
+        "type": "object",
+        "properties": {
+          "number": {"type": "integer", "format": "uint64"},
+          "hash": {"$ref": "#/components/schemas/Hash"},
+          "parentHash": {"$ref": "#/components/schemas/Hash"},
+          "timestamp": {"type": "integer", "format": "uint64"},
+          "txCount": {"type": "integer"},
+          "transactions": {"type": "array", "items": {"$ref": "#/components/schemas/Transaction"}}
+        }
+      },
+      "Transaction": {
+        "type": "object",
+        "description": "Block fields and status are only set once the transaction is committed.",
+        "properties": {
+          "hash": {"$ref": "#/components/schemas/Hash"},
+          "type": {"$ref": "#/components/schemas/TransactionType"},
+          "from": {"$ref": "#/components/schemas/Address"},
+          "to": {"$ref": "#/components/schemas/Address"},
+          "amount": {"$ref": "#/components/schemas/Amount", "description": "Total of the outputs of a batch transfer"},
+          "outputs": {"type": "array", "items": {"$ref": "#/components/schemas/TxOutput"}},
+          "reference": {"$ref": "#/components/schemas/Reference"},
+          "nonce": {"type": "integer", "format": "uint64", "description": "Nonce of a mint"},
+          "blockNumber": {"type": "integer", "format": "uint64"},
+          "blockHash": {"$ref": "#/components/schemas/Hash"},
+          "status": {"type": "integer", "enum": [0, 1]},
+          "pending": {"type": "boolean"}
+        }
+      },
+      "Receipt": {
+        "type": "object",
+        "properties": {
+          "transactionHash": {"$ref": "#/components/schemas/Hash"},
+          "status": {"type": "integer", "enum": [0, 1], "description": "1 when the transfer succeeded"},
+          "blockHash": {"$ref": "#/components/schemas/Hash"},
+          "blockNumber": {"type": "integer", "format": "uint64"},
+          "index": {"type": "integer", "format": "uint64"},
+          "logs": {"type": "array", "description": "Money created or destroyed by the transaction, and outputs paid by a batch transfer", "items": {"$ref": "#/components/schemas/ReceiptLog"}}
+        }
+      },
+      "ReceiptLog": {
+        "type": "object",
+        "properties": {
+          "kind": {"type": "string", "enum": ["mint", "burn", "output"]},
+          "account": {"$ref": "#/components/schemas/Address"},
+          "amount": {"$ref": "#/components/schemas/Amount"}
+        }
+      },
+      "Supply": {
+        "type": "object",
+        "properties": {
+          "blockNumber": {"type": "integer", "format": "uint64"},
+          "totalSupply": {"$ref": "#/components/schemas/Amount"},
+          "minted": {"$ref": "#/components/schemas/Amount"},
+          "burned": {"$ref": "#/components/schemas/Amount", "description": "Destroyed by burn transactions"},
+          "demurrageBurned": {"$ref": "#/components/schemas/Amount"},
+          "accounts": {"type": "integer", "format": "uint64", "description": "Number of accounts holding money"},
+          "cap": {"$ref": "#/components/schemas/Amount"}
+        }
+      },
+      "Holder": {
+        "type": "object",
+        "properties": {
+          "address": {"$ref": "#/components/schemas/Address"},
+          "balance": {"$ref": "#/components/schemas/Amount"}
+        }
+      },
+      "Account": {
+        "type": "object",
+        "properties": {
+          "address": {"$ref": "#/components/schemas/Address"},
+          "balance": {"$ref": "#/components/schemas/Amount"},
+          "transactions": {"type": "array", "items": {"$ref": "#/components/schemas/Transaction"}}
+        }
+      },
+      "DemurrageCharge": {
+        "type": "object",
+        "description": "Demurrage charged to an account by a block, and its share of the collected amount when it is redistributed or credited to the treasury",
+        "properties": {
+          "block": {"type": "integer"},
+          "address": {"$ref": "#/components/schemas/Address"},
+          "amount": {"$ref": "#/components/schemas/Amount"},
+          "share": {"$ref": "#/components/schemas/Amount"}
+        }
+      },
+      "TransactionType": {"type": "integer", "enum": [0, 1, 2, 3, 4], "description": "0 for a transfer, 1 for a mint, 2 for a burn, 3 for a batch transfer, 4 for a payment carrying a reference. Mints are sent from, and burns to, the zero address."},
+      "TxOutput": {
+        "type": "object",
+        "required": ["to", "amount"],
+        "properties": {
+          "to": {"$ref": "#/components/schemas/Address"},
+          "amount": {"$ref": "#/components/schemas/Amount", "description": "Must be positive"}
+        }
+      },
+      "TransactionRequest": {
+        "type": "object",
+        "required": ["signature"],
+        "properties": {
+          "type": {"$ref": "#/components/schemas/TransactionType"},
+          "from": {"$ref": "#/components/schemas/Address", "description": "Sender of transfers, burns and batch transfers"},
+          "to": {"$ref": "#/components/schemas/Address", "description": "Recipient of transfers, payments and mints"},
+          "amount": {"$ref": "#/components/schemas/Amount", "description": "Must be positive. Ignored for batch transfers"},
+          "outputs": {"type": "array", "description": "Payments of a batch transfer, applied all or none", "maxItems": 512, "items": {"$ref": "#/components/schemas/TxOutput"}},
+          "reference": {"$ref": "#/components/schemas/Reference"},
+          "nonce": {"type": "integer", "format": "uint64", "description": "Nonce of a mint, telling it apart from the committed mints of the same amount to the same account"},
+          "signature": {"type": "string", "description": "hex encoded 65 bytes [R || S || V] secp256k1 signature of keccak256(rlp([chainId, from, to, amount])) for a transfer, of keccak256(rlp([chainId, type, from, amount])) for a burn, of keccak256(rlp([chainId, type, from, [[to, amount]...]])) for a batch transfer, and of keccak256(rlp([chainId, type, from, to, amount, reference])) for a payment. Mints carry the concatenated signatures of their issuers over keccak256(rlp([chainId, type, to, amount, nonce]))."}
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string", "readOnly": true},
+          "url": {"type": "string"},
+          "addresses": {"type": "array", "items": {"$ref": "#/components/schemas/Address"}},
+          "confirmations": {"type": "integer"},
+          "secret": {"type": "string", "writeOnly": true},
+          "next": {"type": "integer", "readOnly": true}
+        }
+      },
+      "Probe": {
+        "type": "object",
+        "properties": {
+          "status": {"type": "string", "enum": ["ok", "failing"]},
+          "checks": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "object",
+              "properties": {"ok": {"type": "boolean"}, "detail": {"type": "string"}}
+            }
+          }
+        }
+      },
+      "WsRequest": {
+        "description": "Messages sent by websocket clients",
+        "oneOf": [
+          {
+            "type": "object",
+            "title": "subscribe",
+            "properties": {
+              "type": {"type": "string", "enum": ["subscribe"]},
+              "data": {
+                "type": "object",
+                "properties": {
+                  "topic": {"type": "string", "enum": ["newHeads", "newPendingTransactions", "logs", "syncing"]},
+                  "address": {"$ref": "#/components/schemas/Address"}
+                },
+                "description": "address filters the logs topic"
+              }
+            }
+          },
+          {
+            "type": "object",
+            "title": "unsubscribe",
+            "properties": {
+              "type": {"type": "string", "enum": ["unsubscribe"]},
+              "data": {"type": "object", "properties": {"id": {"type": "string"}}}
+            }
+          },
+          {
+            "type": "object",
+            "title": "network-state",
+            "properties": {"type": {"type": "string", "enum": ["network-state"]}}
+          }
+        ]
+      },
+      "WsMessage": {
+        "description": "Messages sent to websocket clients. ibftEventIn, ibftEventOut and txEvent relay consensus events and name the Go type of their data in dataType.",
+        "type": "object",
+        "properties": {
+          "type": {"type": "string", "enum": ["connection", "error", "subscribed", "unsubscribed", "subscription", "network-state", "ibftEventIn", "ibftEventOut", "txEvent"]},
+          "data": {
+            "oneOf": [
+              {"type": "string", "title": "connection and error"},
+              {"type": "object", "title": "subscribed", "properties": {"id": {"type": "string"}, "topic": {"type": "string"}}},
+              {"type": "object", "title": "unsubscribed", "properties": {"id": {"type": "string"}}},
+              {"$ref": "#/components/schemas/SubscriptionResult"},
+              {"type": "object", "title": "network-state", "description": "remote address of each validator", "additionalProperties": {"type": "string"}},
+              {"type": "object", "title": "consensus event"}
+            ]
+          },
+          "dataType": {"type": "string"}
+        }
+      },
+      "SubscriptionResult": {
+        "type": "object",
+        "properties": {
+          "subscription": {"type": "string"},
+          "result": {
+            "oneOf": [
+              {
+                "type": "object",
+                "title": "newHeads",
+                "properties": {"hash": {}, "header": {"type": "object", "properties": {"number": {"type": "integer"}, "parenthash": {}, "timestamp": {"type": "integer"}}}}
+              },
+              {
+                "type": "object",
+                "title": "newPendingTransactions",
+                "properties": {"from": {}, "to": {}, "amount": {"type": "integer"}, "signature": {}}
+              },
+              {
+                "type": "object",
+                "title": "logs",
+                "properties": {"blockNumber": {"type": "integer"}, "blockHash": {}, "txHash": {}, "from": {}, "to": {}, "amount": {"type": "integer"}, "reference": {"$ref": "#/components/schemas/Reference"}, "status": {"type": "integer"}}
+              },
+              {
+                "type": "object",
+                "title": "syncing",
+                "properties": {"syncing": {"type": "boolean"}, "currentBlock": {"type": "integer"}}
+              }
+            ]
+          }
+        }
+      }
+    }
+  },
+  "security": [{}, {"bearer": []}, {"apiKey": []}, {"accessToken": []}],
+  "paths": {
+    "/ws": {
+      "get": {
+        "summary": "Websocket of subscriptions and consensus events",
+        "description": "Clients send WsRequest messages and receive WsMessage messages.",
+        "x-scope": "read",
+        "responses": {"101": {"description": "Switching to the websocket protocol"}}
+      }
+    },
+    "/hello": {
+      "get": {
+        "summary": "Connectivity check",
+        "x-scope": "read",
+        "responses": {"200": {"description": "Greeting", "content": {"application/json": {"schema": {"type": "string"}}}}}
+      }
+    },
+    "/balance": {
+      "get": {
+        "summary": "Balance of an account",
+        "x-scope": "read",
+        "parameters": [
+          {"$ref": "#/components/parameters/account"},
+          {"name": "block", "in": "query", "description": "balance at the end of this block instead of the head", "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "Balance", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Balance"}}}},
+          "404": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/chain": {
+      "get": {
+        "summary": "Whole blockchain",
+        "x-scope": "read",
+        "responses": {"200": {"description": "Every block of the chain", "content": {"application/json": {"schema": {"type": "object", "properties": {"blockchain": {"type": "array", "items": {"type": "object"}}}}}}}}
+      }
+    },
+    "/blocks": {
+      "get": {
+        "summary": "Latest blocks",
+        "x-scope": "read",
+        "parameters": [
+          {"name": "before", "in": "query", "description": "list the blocks preceding this number", "schema": {"type": "integer"}},
+          {"$ref": "#/components/parameters/count"}
+        ],
+        "responses": {
+          "200": {"description": "Blocks, newest first", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Block"}}}}},
+          "400": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/block": {
+      "get": {
+        "summary": "Block with its transactions",
+        "description": "The head block is returned when neither number nor hash is given.",
+        "x-scope": "read",
+        "parameters": [
+          {"name": "number", "in": "query", "schema": {"type": "integer"}},
+          {"name": "hash", "in": "query", "schema": {"$ref": "#/components/schemas/Hash"}}
+        ],
+        "responses": {
+          "200": {"description": "Block", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Block"}}}},
+          "400": {"$ref": "#/components/responses/error"},
+          "404": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/tx": {
+      "get": {
+        "summary": "Committed or pending transaction",
+        "x-scope": "read",
+        "parameters": [{"$ref": "#/components/parameters/hash"}],
+        "responses": {
+          "200": {"description": "Transaction", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Transaction"}}}},
+          "404": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/receipt": {
+      "get": {
+        "summary": "Receipt of a committed transaction",
+        "x-scope": "read",
+        "parameters": [{"$ref": "#/components/parameters/hash"}],
+        "responses": {
+          "200": {"description": "Receipt", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Receipt"}}}},
+          "404": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/supply": {
+      "get": {
+        "summary": "Accounting of the money supply at the head block, or at the given block",
+        "x-scope": "read",
+        "parameters": [{"name": "block", "in": "query", "schema": {"type": "integer"}}],
+        "responses": {
+          "200": {"description": "Supply", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Supply"}}}},
+          "400": {"$ref": "#/components/responses/error"},
+          "404": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/richlist": {
+      "get": {
+        "summary": "Accounts holding the most money, richest first",
+        "x-scope": "read",
+        "parameters": [{"$ref": "#/components/parameters/count"}],
+        "responses": {
+          "200": {"description": "Holders", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Holder"}}}}},
+          "400": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/submit": {
+      "post": {
+        "summary": "Submit a signed transaction",
+        "x-scope": "submit",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TransactionRequest"}}}},
+        "responses": {
+          "200": {"description": "Hash of the pending transaction", "content": {"application/json": {"schema": {"type": "object", "properties": {"hash": {"$ref": "#/components/schemas/Hash"}}}}}},
+          "400": {"$ref": "#/components/responses/error"},
+          "422": {"description": "Transaction rejected by the node", "content": {"text/plain": {"schema": {"type": "string"}}}}
+        }
+      }
+    },
+    "/address": {
+      "get": {
+        "summary": "Balance and transactions of an account",
+        "x-scope": "read",
+        "parameters": [
+          {"$ref": "#/components/parameters/account"},
+          {"$ref": "#/components/parameters/offset"},
+          {"$ref": "#/components/parameters/count"}
+        ],
+        "responses": {
+          "200": {"description": "Account", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Account"}}}},
+          "400": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/accounts/{address}/transactions": {
+      "get": {
+        "summary": "Transactions of an account, most recent first",
+        "x-scope": "read",
+        "parameters": [
+          {"name": "address", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/Address"}},
+          {"name": "reference", "in": "query", "description": "Only the payments carrying this reference", "schema": {"$ref": "#/components/schemas/Reference"}},
+          {"$ref": "#/components/parameters/offset"},
+          {"$ref": "#/components/parameters/count"}
+        ],
+        "responses": {
+          "200": {"description": "Transactions", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Transaction"}}}}},
+          "400": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/demurrage": {
+      "get": {
+        "summary": "Demurrage charged to an account, most recent first",
+        "x-scope": "read",
+        "parameters": [
+          {"$ref": "#/components/parameters/account"},
+          {"$ref": "#/components/parameters/offset"},
+          {"$ref": "#/components/parameters/count"}
+        ],
+        "responses": {
+          "200": {"description": "Charges", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/DemurrageCharge"}}}}},
+          "400": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/pending": {
+      "get": {
+        "summary": "Transactions waiting to be included in a block",
+        "x-scope": "read",
+        "responses": {"200": {"description": "Pending transactions", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Transaction"}}}}}}
+      }
+    },
+    "/explorer": {
+      "get": {
+        "summary": "Block explorer web page",
+        "x-scope": "read",
+        "responses": {"200": {"description": "Explorer", "content": {"text/html": {}}}}
+      }
+    },
+    "/graphql": {
+      "get": {
+        "summary": "GraphQL query",
+        "x-scope": "read",
+        "parameters": [
+          {"name": "query", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "operationName", "in": "query", "schema": {"type": "string"}},
+          {"name": "variables", "in": "query", "description": "JSON encoded variables", "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"description": "GraphQL response", "content": {"application/json": {}}}}
+      },
+      "post": {
+        "summary": "GraphQL query",
+        "x-scope": "read",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"type": "object", "properties": {"query": {"type": "string"}, "operationName": {"type": "string"}, "variables": {"type": "object"}}}}}},
+        "responses": {"200": {"description": "GraphQL response", "content": {"application/json": {}}}}
+      }
+    },
+    "/state": {
+      "get": {
+        "summary": "RLP encoded blockchain and pending transactions",
+        "x-scope": "sync",
+        "responses": {"200": {"description": "State", "content": {"application/octet-stream": {}}}}
+      }
+    },
+    "/export": {
+      "get": {
+        "summary": "Stream of RLP encoded blocks",
+        "x-scope": "sync",
+        "parameters": [
+          {"name": "from", "in": "query", "schema": {"type": "integer", "default": 0}},
+          {"name": "to", "in": "query", "description": "last exported block, the head by default", "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "Blocks", "headers": {"X-Chain-Head": {"description": "number of the head block", "schema": {"type": "integer"}}}, "content": {"application/octet-stream": {}}},
+          "400": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/import": {
+      "post": {
+        "summary": "Insert a stream of RLP encoded blocks, as served by /export",
+        "x-scope": "admin",
+        "requestBody": {"required": true, "content": {"application/octet-stream": {}}},
+        "responses": {
+          "200": {"description": "Import result", "content": {"application/json": {"schema": {"type": "object", "properties": {"imported": {"type": "integer"}, "head": {"type": "integer"}}}}}},
+          "400": {"$ref": "#/components/responses/error"},
+          "409": {"description": "The consensus is running"}
+        }
+      }
+    },
+    "/logs": {
+      "get": {
+        "summary": "Node output",
+        "x-scope": "admin",
+        "responses": {"200": {"description": "Logs", "content": {"text/plain": {}}}, "404": {"description": "Logs are disabled"}}
+      }
+    },
+    "/webhooks": {
+      "get": {
+        "summary": "Registered webhooks",
+        "x-scope": "admin",
+        "responses": {"200": {"description": "Webhooks", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Webhook"}}}}}}
+      },
+      "post": {
+        "summary": "Register a webhook notified of the transfers of addresses",
+        "x-scope": "admin",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Webhook"}}}},
+        "responses": {
+          "201": {"description": "Registered webhook", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Webhook"}}}},
+          "400": {"$ref": "#/components/responses/error"}
+        }
+      },
+      "delete": {
+        "summary": "Unregister a webhook",
+        "x-scope": "admin",
+        "parameters": [{"name": "id", "in": "query", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "Unregistered"}, "404": {"$ref": "#/components/responses/error"}}
+      }
+    },
+    "/metrics": {
+      "get": {
+        "summary": "Prometheus metrics",
+        "x-scope": "admin",
+        "responses": {"200": {"description": "Metrics", "content": {"text/plain": {}}}}
+      }
+    },
+    "/healthz": {
+      "get": {
+        "summary": "Liveness probe",
+        "security": [],
+        "responses": {
+          "200": {"description": "Healthy", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Probe"}}}},
+          "503": {"description": "Unhealthy", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Probe"}}}}
+        }
+      }
+    },
+    "/readyz": {
+      "get": {
+        "summary": "Readiness probe",
+        "security": [],
+        "responses": {
+          "200": {"description": "Ready", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Probe"}}}},
+          "503": {"description": "Not ready", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Probe"}}}}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "x-scope": "read",
+        "responses": {"200": {"description": "OpenAPI document", "content": {"application/json": {}}}}
+      }
+    }
+  }
+}
+`
+
+func (ep *Endpoint) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(openAPISpec))
+}
@@ -0,0 +1,29 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	spec := struct {
+		Paths map[string]interface{} `json:"paths"`
+	}{}
+	if err := json.Unmarshal([]byte(openAPISpec), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	ep := New()
+	for path := range spec.Paths {
+		// Path parameters are routed by the subtree before the first one
+		route := path
+		if i := strings.Index(path, "{"); i >= 0 {
+			route = path[:i]
+		}
+		if _, pattern := ep.mux.Handler(httptest.NewRequest("GET", path, nil)); pattern != route {
+			t.Errorf("documented path %s is not routed", path)
+		}
+	}
+}
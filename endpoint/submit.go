@@ -0,0 +1,170 @@
+package endpoint
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+var (
+	errInvalidAmount    = errors.New("invalid amount")
+	errInvalidSignature = errors.New("invalid signature")
+	errInvalidReference = errors.New("invalid reference")
+)
+
+// txRequest is the JSON body of a transaction submission. The signature is
+// made by types.SignTx over the signing hash of the transaction.
+type txRequest struct {
+	Type      uint8             `json:"type"`
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Amount    string            `json:"amount"`
+	Outputs   []txOutputRequest `json:"outputs"`
+	Reference string            `json:"reference"`
+	Nonce     uint64            `json:"nonce"`
+	Signature string            `json:"signature"`
+}
+
+type txOutputRequest struct {
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+func parseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, errInvalidAmount
+	}
+	return amount, nil
+}
+
+// transaction returns the transaction of the request. The sender of mints, the
+// recipient of burns, the amount of batch transfers, the reference of all but
+// payments and the nonce of all but mints are not part of their type and are
+// ignored.
+func (req *txRequest) transaction() (*types.Transaction, error) {
+	signature, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return nil, errInvalidSignature
+	}
+	if req.Type == types.BatchTxType {
+		tx, err := req.batch()
+		if err != nil {
+			return nil, err
+		}
+		tx.Signature = signature
+		return tx, nil
+	}
+	amount, err := parseAmount(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	var tx *types.Transaction
+	switch req.Type {
+	case types.LegacyTxType:
+		from, err := parseAddress(req.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseAddress(req.To)
+		if err != nil {
+			return nil, err
+		}
+		tx = types.NewTransaction(from, to, amount)
+	case types.MintTxType:
+		to, err := parseAddress(req.To)
+		if err != nil {
+			return nil, err
+		}
+		tx = types.NewMint(to, amount)
+		tx.Nonce = req.Nonce
+	case types.BurnTxType:
+		from, err := parseAddress(req.From)
+		if err != nil {
+			return nil, err
+		}
+		tx = types.NewBurn(from, amount)
+	case types.PaymentTxType:
+		from, err := parseAddress(req.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseAddress(req.To)
+		if err != nil {
+			return nil, err
+		}
+		reference, err := hex.DecodeString(req.Reference)
+		if err != nil {
+			return nil, errInvalidReference
+		}
+		if len(reference) > types.MaxReferenceLength {
+			return nil, types.ErrReferenceTooLong
+		}
+		tx = types.NewPayment(from, to, amount, reference)
+	default:
+		return nil, types.ErrTxTypeNotSupported
+	}
+	tx.Signature = signature
+	return tx, nil
+}
+
+// batch returns the batch transfer of the request
+func (req *txRequest) batch() (*types.Transaction, error) {
+	from, err := parseAddress(req.From)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Outputs) == 0 || len(req.Outputs) > types.MaxBatchOutputs {
+		return nil, types.ErrBatchSize
+	}
+	outputs := make([]*types.TxOutput, 0, len(req.Outputs))
+	for _, output := range req.Outputs {
+		to, err := parseAddress(output.To)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := parseAmount(output.Amount)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, &types.TxOutput{To: to, Amount: amount})
+	}
+	return types.NewBatch(from, outputs), nil
+}
+
+// submitHandler verifies a signed transaction and adds it to the pending
+// transactions. It answers the hash of the transaction.
+func (ep *Endpoint) submitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req := txRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<18)).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tx, err := req.transaction()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from, err := types.Sender(tx); !tx.IsMint() && (err != nil || from != tx.From) {
+		http.Error(w, errInvalidSignature.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err := ep.Currency.SubmitTransaction(tx); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Pending transactions are stored, and hashed, in their pool form
+	hash := tx.PoolCopy().Hash()
+	writeJSON(w, struct {
+		Hash string `json:"hash"`
+	}{hex.EncodeToString(hash.Bytes())})
+}
@@ -0,0 +1,14 @@
+package endpoint
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	if amount, err := parseAmount("42"); err != nil || amount.Int64() != 42 {
+		t.Fatalf("parseAmount(\"42\") = %v, %v", amount, err)
+	}
+	for _, s := range []string{"", "0", "-1", "1.5", "0x10"} {
+		if _, err := parseAmount(s); err != errInvalidAmount {
+			t.Errorf("parseAmount(%q) returned %v, want %v", s, err, errInvalidAmount)
+		}
+	}
+}
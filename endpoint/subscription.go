@@ -0,0 +1,257 @@
+package endpoint
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+// Topics a websocket client can subscribe to
+const (
+	topicNewHeads   = "newHeads"
+	topicPendingTxs = "newPendingTransactions"
+	topicLogs       = "logs"
+	topicSyncing    = "syncing"
+	randomIDLength  = 16
+)
+
+type subscribeRequest struct {
+	Topic   string `json:"topic"`
+	Address string `json:"address"`
+}
+
+type unsubscribeRequest struct {
+	ID string `json:"id"`
+}
+
+// subscription is a topic a client asked to be notified about. The address
+// filters the logs topic and is ignored by the others.
+type subscription struct {
+	ID      string       `json:"id"`
+	Topic   string       `json:"topic"`
+	Address ibft.Address `json:"-"`
+}
+
+// subscriptionResult is the payload sent to a client for each notification
+type subscriptionResult struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// notification is an event dispatched by the hub to matching subscriptions.
+// A logs notification carries the transfers of a whole block in logs, so that
+// a large batch takes a single slot of the queue.
+type notification struct {
+	topic string
+	data  interface{}
+	logs  []transferLog
+}
+
+// results returns the payloads of a notification for a subscription: the
+// transfers concerning its address for the logs topic, the data otherwise
+func (n *notification) results(sub *subscription) []interface{} {
+	if n.topic != topicLogs {
+		return []interface{}{n.data}
+	}
+	results := []interface{}{}
+	for _, log := range n.logs {
+		if log.From == sub.Address || log.To == sub.Address {
+			results = append(results, log)
+		}
+	}
+	return results
+}
+
+type newHead struct {
+	Hash   ibft.Hash     `json:"hash"`
+	Header *types.Header `json:"header"`
+}
+
+// transferLog describes a transfer from the point of view of a subscribed
+// address. The reference of a payment is hex encoded.
+type transferLog struct {
+	BlockNumber *big.Int     `json:"blockNumber"`
+	BlockHash   ibft.Hash    `json:"blockHash"`
+	TxHash      ibft.Hash    `json:"txHash"`
+	From        ibft.Address `json:"from"`
+	To          ibft.Address `json:"to"`
+	Amount      *big.Int     `json:"amount"`
+	Reference   string       `json:"reference,omitempty"`
+	Status      uint64       `json:"status"`
+}
+
+type syncStatus struct {
+	Syncing      bool   `json:"syncing"`
+	CurrentBlock uint64 `json:"currentBlock"`
+}
+
+// subscriptions is the set of subscriptions of a client. It is written by the
+// client read pump and read by the hub.
+type subscriptions struct {
+	mu   sync.RWMutex
+	subs map[string]*subscription
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{subs: make(map[string]*subscription)}
+}
+
+func (s *subscriptions) add(sub *subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.ID] = sub
+}
+
+func (s *subscriptions) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[id]; !ok {
+		return false
+	}
+	delete(s.subs, id)
+	return true
+}
+
+// match returns the subscriptions interested in a notification
+func (s *subscriptions) match(n *notification) []*subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches := []*subscription{}
+	for _, sub := range s.subs {
+		if sub.Topic == n.topic {
+			matches = append(matches, sub)
+		}
+	}
+	return matches
+}
+
+// randomID returns a random hex identifier
+func randomID() (string, error) {
+	id := make([]byte, randomIDLength)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func (ep *Endpoint) handleSubscribe(data json.RawMessage, cli *Client) {
+	req := subscribeRequest{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		cli.sendError("invalid subscribe request: " + err.Error())
+		return
+	}
+
+	sub := &subscription{Topic: req.Topic}
+	switch req.Topic {
+	case topicNewHeads, topicPendingTxs, topicSyncing:
+	case topicLogs:
+		bytes, err := hex.DecodeString(req.Address)
+		if err != nil || len(bytes) != len(sub.Address) {
+			cli.sendError("invalid address: " + req.Address)
+			return
+		}
+		sub.Address.FromBytes(bytes)
+	default:
+		cli.sendError("unknown topic: " + req.Topic)
+		return
+	}
+
+	id, err := randomID()
+	if err != nil {
+		cli.sendError("failed to create subscription: " + err.Error())
+		return
+	}
+	sub.ID = id
+	cli.subs.add(sub)
+
+	cli.reply(message{
+		Type: "subscribed",
+		Data: sub,
+	})
+}
+
+func (ep *Endpoint) handleUnsubscribe(data json.RawMessage, cli *Client) {
+	req := unsubscribeRequest{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		cli.sendError("invalid unsubscribe request: " + err.Error())
+		return
+	}
+	if !cli.subs.remove(req.ID) {
+		cli.sendError("unknown subscription: " + req.ID)
+		return
+	}
+	cli.reply(message{
+		Type: "unsubscribed",
+		Data: req,
+	})
+}
+
+// notify queues a notification for the hub. Notifications are dropped when the
+// hub is not keeping up so that consensus is never blocked by slow clients.
+func (ep *Endpoint) notify(n *notification) {
+	select {
+	case ep.notifications <- n:
+	default:
+		ep.debug.Warningf("notification queue full, dropping %s event", n.topic)
+	}
+}
+
+// PublishBlock notifies subscribers of a new head and of the transfers it
+// contains, one for each output of a batch transfer. Receipts are matched with
+// transactions by index.
+func (ep *Endpoint) PublishBlock(block *types.Block, receipts types.Receipts) {
+	hash := block.Hash()
+	ep.notify(&notification{
+		topic: topicNewHeads,
+		data:  newHead{Hash: hash, Header: block.Header},
+	})
+	if ep.webhooks != nil && ep.Currency != nil {
+		ep.webhooks.commit(ep.Currency.BlockChain(), block.Number().Uint64())
+	}
+
+	logs := []transferLog{}
+	for i, tx := range block.Transactions {
+		status := types.ReceiptStatusFailed
+		if i < len(receipts) && receipts[i] != nil {
+			status = receipts[i].Status
+		}
+		txHash := tx.Hash()
+		for _, payment := range tx.Payments() {
+			logs = append(logs, transferLog{
+				BlockNumber: block.Number(),
+				BlockHash:   hash,
+				TxHash:      txHash,
+				From:        tx.From,
+				To:          payment.To,
+				Amount:      payment.Amount,
+				Reference:   hex.EncodeToString(tx.Reference),
+				Status:      status,
+			})
+		}
+	}
+	if len(logs) > 0 {
+		ep.notify(&notification{topic: topicLogs, logs: logs})
+	}
+}
+
+// PublishPendingTransaction notifies subscribers of a transaction added to the
+// pending pool
+func (ep *Endpoint) PublishPendingTransaction(tx *types.Transaction) {
+	ep.notify(&notification{
+		topic: topicPendingTxs,
+		data:  tx,
+	})
+}
+
+// PublishSyncing notifies subscribers that a synchronization started or ended
+func (ep *Endpoint) PublishSyncing(syncing bool, currentBlock uint64) {
+	ep.notify(&notification{
+		topic: topicSyncing,
+		data:  syncStatus{Syncing: syncing, CurrentBlock: currentBlock},
+	})
+}
@@ -0,0 +1,104 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/gorilla/websocket"
+)
+
+func readMessage(t *testing.T, conn *websocket.Conn) map[string]json.RawMessage {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg := map[string]json.RawMessage{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func expectType(t *testing.T, msg map[string]json.RawMessage, want string) {
+	if got := string(msg["type"]); got != `"`+want+`"` {
+		t.Fatalf("got message %s %s, want %s", got, msg["data"], want)
+	}
+}
+
+func TestSubscribeLogs(t *testing.T) {
+	addr := freeAddr()
+	ep := New()
+	go ep.Start(addr)
+	waitForServer(addr)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ep.Stop(ctx)
+	}()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	expectType(t, readMessage(t, conn), "connection")
+
+	sender, other := ibft.Address{1}, ibft.Address{2}
+	conn.WriteJSON(map[string]interface{}{"type": "subscribe", "data": map[string]string{"topic": "mempool"}})
+	expectType(t, readMessage(t, conn), "error")
+	conn.WriteJSON(map[string]interface{}{"type": "subscribe", "data": map[string]string{"topic": topicLogs, "address": "0100000000000000000000000000000000000000"}})
+	msg := readMessage(t, conn)
+	expectType(t, msg, "subscribed")
+	sub := subscription{}
+	json.Unmarshal(msg["data"], &sub)
+
+	// A full batch is a single notification, and its sender gets every output
+	outputs := make([]*types.TxOutput, types.MaxBatchOutputs)
+	for i := range outputs {
+		outputs[i] = &types.TxOutput{To: other, Amount: big.NewInt(int64(i + 1))}
+	}
+	batch := types.NewBatch(sender, outputs)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1), Time: big.NewInt(0)}, types.Transactions{
+		types.NewTransaction(other, ibft.Address{3}, big.NewInt(5)),
+		batch,
+	})
+	ep.PublishBlock(block, types.Receipts{
+		types.NewReceipt(block.Transactions[0].Hash(), types.ReceiptStatusSuccessful),
+		types.NewReceipt(batch.Hash(), types.ReceiptStatusSuccessful),
+	})
+	for i := range outputs {
+		msg := readMessage(t, conn)
+		expectType(t, msg, "subscription")
+		result := struct {
+			Subscription string
+			Result       transferLog
+		}{}
+		json.Unmarshal(msg["data"], &result)
+		if result.Subscription != sub.ID || result.Result.From != sender || result.Result.Amount.Int64() != int64(i+1) {
+			t.Fatalf("got notification %s for output %d", msg["data"], i)
+		}
+	}
+
+	conn.WriteJSON(map[string]interface{}{"type": "unsubscribe", "data": map[string]string{"id": sub.ID}})
+	expectType(t, readMessage(t, conn), "unsubscribed")
+	conn.WriteJSON(map[string]interface{}{"type": "unsubscribe", "data": map[string]string{"id": sub.ID}})
+	expectType(t, readMessage(t, conn), "error")
+}
+
+func TestReplyToDroppedClient(t *testing.T) {
+	ep := New()
+	defer ep.quitOnce.Do(func() { close(ep.quit) })
+	client := &Client{ep: ep, send: make(chan interface{}, 1), subs: newSubscriptions()}
+	ep.register <- client
+	ep.unregister <- client
+
+	// Replies of the read pump after the hub closed the send channel are
+	// dropped instead of panicking
+	client.sendError("late")
+	client.sendError("late")
+	if _, ok := <-client.send; ok {
+		t.Fatal("got a reply for a dropped client")
+	}
+}
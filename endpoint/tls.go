@@ -0,0 +1,57 @@
+package endpoint
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+)
+
+var (
+	tlsCertFile     = flag.String("tls-cert", "", "endpoint TLS certificate file (enables https)")
+	tlsKeyFile      = flag.String("tls-key", "", "endpoint TLS private key file")
+	tlsClientCAFile = flag.String("tls-client-ca", "", "CA bundle verifying peer certificates on sync routes (enables mutual TLS)")
+)
+
+// LoadCertPool reads a PEM encoded CA bundle
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificate found in " + file)
+	}
+	return pool, nil
+}
+
+// tlsEnabled reports whether the endpoint serves https
+func tlsEnabled() bool {
+	return *tlsCertFile != "" && *tlsKeyFile != ""
+}
+
+// serverTLSConfig returns the TLS configuration of the endpoint. Client
+// certificates are verified when given so that public routes stay reachable
+// without one; sync routes then require a verified certificate.
+func serverTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if *tlsClientCAFile == "" {
+		return config, nil
+	}
+	pool, err := LoadCertPool(*tlsClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return config, nil
+}
+
+// hasPeerCertificate reports whether a request was made with a client
+// certificate verified against the -tls-client-ca bundle
+func hasPeerCertificate(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
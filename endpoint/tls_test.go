@@ -0,0 +1,168 @@
+package endpoint
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and its key, PEM encoded in files
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA
+// when parent is nil, and writes it to dir
+func newTestCert(t *testing.T, dir string, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	issuer, signer := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".pem"),
+		keyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+	if err := ioutil.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, dir, "ca", nil)
+	server := newTestCert(t, dir, "server", ca)
+	peer := newTestCert(t, dir, "peer", ca)
+	stranger := newTestCert(t, dir, "stranger", newTestCert(t, dir, "other-ca", nil))
+
+	defer func(cert, key, clientCA string) {
+		*tlsCertFile, *tlsKeyFile, *tlsClientCAFile = cert, key, clientCA
+	}(*tlsCertFile, *tlsKeyFile, *tlsClientCAFile)
+	*tlsCertFile, *tlsKeyFile, *tlsClientCAFile = server.certFile, server.keyFile, ca.certFile
+
+	cur, cleanup := newTestCurrency()
+	defer cleanup()
+	ep := New()
+	ep.Currency = cur
+	addr := freeAddr()
+	go ep.Start(addr)
+	defer ep.Stop(context.Background())
+	waitForServer(addr)
+
+	roots, err := LoadCertPool(ca.certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string, cert *testCert) (int, error) {
+		config := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			pair, err := tls.LoadX509KeyPair(cert.certFile, cert.keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.Certificates = []tls.Certificate{pair}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := client.Get("https://" + addr + path)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	// Public routes are served over https without a client certificate
+	if code, err := get("/hello", nil); err != nil || code != http.StatusOK {
+		t.Fatalf("GET /hello: got %d (%v)", code, err)
+	}
+	if resp, err := http.Get("http://" + addr + "/hello"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Fatal("the endpoint answered plain http")
+		}
+	}
+
+	// Sync routes require a peer certificate signed by the client CA
+	if code, err := get("/export", nil); err != nil || code != http.StatusForbidden {
+		t.Fatalf("GET /export without a certificate: got %d (%v), want %d", code, err, http.StatusForbidden)
+	}
+	if code, err := get("/export", peer); err != nil || code != http.StatusOK {
+		t.Fatalf("GET /export with a peer certificate: got %d (%v)", code, err)
+	}
+	if code, err := get("/export", stranger); err == nil && code != http.StatusForbidden {
+		t.Fatalf("GET /export with a certificate of an unknown CA: got %d", code)
+	}
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, dir, "ca", nil)
+	if _, err := LoadCertPool(ca.certFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCertPool(ca.keyFile); err == nil {
+		t.Fatal("loaded a CA bundle without certificates")
+	}
+	if _, err := LoadCertPool(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Fatal("loaded a missing CA bundle")
+	}
+}
@@ -1,5 +1,7 @@
 package endpoint
 
+import "encoding/json"
+
 const (
 	inboundDir uint = iota
 	outboundDir
@@ -11,3 +13,10 @@ type message struct {
 	Data     interface{} `json:"data"`
 	DataType string      `json:"dataType"`
 }
+
+// request is a message received from a websocket client. Its data is decoded
+// depending on the request type.
+type request struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
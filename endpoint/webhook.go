@@ -0,0 +1,429 @@
+package endpoint
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/rawdb"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// Header carrying the hex encoded HMAC-SHA256 of the request body.
+	webhookSignatureHeader = "X-Slash-Signature"
+
+	webhookRetryBase   = 5 * time.Second
+	webhookRetryMax    = 10 * time.Minute
+	webhookMaxAttempts = 12
+	webhookPollPeriod  = time.Second
+	webhookPostTimeout = 10 * time.Second
+)
+
+var (
+	webhookDataPath = flag.String("webhooks", "", "webhook registry storage path (empty disables webhooks)")
+
+	webhookPrefix  = []byte("w") // webhookPrefix + id -> webhook
+	deliveryPrefix = []byte("d") // deliveryPrefix + id -> pending delivery
+
+	errWebhooksDisabled = errors.New("webhooks are disabled on this node")
+)
+
+// blockSource gives access to the committed blocks and their receipts
+type blockSource interface {
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash ibft.Hash) types.Receipts
+}
+
+// webhook is a registered receiver of the transfers involving a set of
+// addresses. Next is the number of the next block to scan for it.
+type webhook struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Addresses     []string `json:"addresses"`
+	Confirmations uint64   `json:"confirmations"`
+	Secret        string   `json:"secret,omitempty"`
+	Next          uint64   `json:"next"`
+
+	addresses []ibft.Address
+}
+
+// webhookPayload is the signed JSON body POSTed to a webhook
+type webhookPayload struct {
+	Webhook       string      `json:"webhook"`
+	Confirmations uint64      `json:"confirmations"`
+	Transfer      transferLog `json:"transfer"`
+}
+
+// delivery is a payload waiting to be accepted by a webhook
+type delivery struct {
+	ID          string          `json:"id"`
+	WebhookID   string          `json:"webhook"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+}
+
+// webhookRegistry stores the webhooks and their delivery queue
+type webhookRegistry struct {
+	db       *leveldb.DB
+	client   *http.Client
+	mu       sync.Mutex
+	webhooks map[string]*webhook
+	// Latest committed head, waiting to be processed by run
+	heads chan chainHead
+}
+
+// chainHead is a committed head and the chain holding its blocks
+type chainHead struct {
+	bc     blockSource
+	number uint64
+}
+
+func newWebhookRegistry(db *leveldb.DB) (*webhookRegistry, error) {
+	reg := &webhookRegistry{
+		db:       db,
+		client:   &http.Client{Timeout: webhookPostTimeout},
+		webhooks: make(map[string]*webhook),
+		heads:    make(chan chainHead, 1),
+	}
+
+	it := db.NewIterator(util.BytesPrefix(webhookPrefix), nil)
+	defer it.Release()
+	for it.Next() {
+		hook := &webhook{}
+		if err := json.Unmarshal(it.Value(), hook); err != nil {
+			return nil, err
+		}
+		if err := hook.parseAddresses(); err != nil {
+			return nil, err
+		}
+		reg.webhooks[hook.ID] = hook
+	}
+	return reg, it.Error()
+}
+
+func (h *webhook) parseAddresses() error {
+	h.addresses = make([]ibft.Address, 0, len(h.Addresses))
+	for _, a := range h.Addresses {
+		raw, err := hex.DecodeString(a)
+		if err != nil || len(raw) != len(ibft.Address{}) {
+			return errors.New("invalid address: " + a)
+		}
+		addr := ibft.Address{}
+		addr.FromBytes(raw)
+		h.addresses = append(h.addresses, addr)
+	}
+	return nil
+}
+
+func (reg *webhookRegistry) put(prefix []byte, id string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return reg.db.Put(append(append([]byte{}, prefix...), id...), data, nil)
+}
+
+func (reg *webhookRegistry) delete(prefix []byte, id string) error {
+	return reg.db.Delete(append(append([]byte{}, prefix...), id...), nil)
+}
+
+// register validates and stores a new webhook. Only the blocks following
+// head are scanned for it.
+func (reg *webhookRegistry) register(hook *webhook, head uint64) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("invalid webhook url: " + hook.URL)
+	}
+	if len(hook.Addresses) == 0 {
+		return errors.New("a webhook needs at least one address")
+	}
+	if err := hook.parseAddresses(); err != nil {
+		return err
+	}
+	if hook.ID, err = randomID(); err != nil {
+		return err
+	}
+	if hook.Secret == "" {
+		if hook.Secret, err = randomID(); err != nil {
+			return err
+		}
+	}
+	hook.Next = head + 1
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if err := reg.put(webhookPrefix, hook.ID, hook); err != nil {
+		return err
+	}
+	reg.webhooks[hook.ID] = hook
+	return nil
+}
+
+func (reg *webhookRegistry) unregister(id string) bool {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, ok := reg.webhooks[id]; !ok {
+		return false
+	}
+	delete(reg.webhooks, id)
+	reg.delete(webhookPrefix, id)
+	return true
+}
+
+// list returns the registered webhooks without their secret
+func (reg *webhookRegistry) list() []webhook {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	hooks := make([]webhook, 0, len(reg.webhooks))
+	for _, hook := range reg.webhooks {
+		h := *hook
+		h.Secret = ""
+		hooks = append(hooks, h)
+	}
+	return hooks
+}
+
+// commit hands a new head to run without waiting. A head not processed yet is
+// replaced, as process scans every block from the next one of each webhook.
+func (reg *webhookRegistry) commit(bc blockSource, head uint64) {
+	h := chainHead{bc: bc, number: head}
+	for {
+		select {
+		case reg.heads <- h:
+			return
+		default:
+		}
+		select {
+		case <-reg.heads:
+		default:
+		}
+	}
+}
+
+// process queues a delivery for each matching transfer of the blocks that
+// reached the confirmation depth of a webhook now that head is committed.
+func (reg *webhookRegistry) process(bc blockSource, head uint64) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for _, hook := range reg.webhooks {
+		for ; hook.Next+hook.Confirmations <= head; hook.Next++ {
+			block := bc.GetBlockByNumber(hook.Next)
+			if block == nil {
+				break
+			}
+			reg.queueTransfers(hook, block, bc.GetReceiptsByHash(block.Hash()))
+		}
+		reg.put(webhookPrefix, hook.ID, hook)
+	}
+}
+
+func (reg *webhookRegistry) queueTransfers(hook *webhook, block *types.Block, receipts types.Receipts) {
+	for i, tx := range block.Transactions {
+		status := types.ReceiptStatusFailed
+		if i < len(receipts) && receipts[i] != nil {
+			status = receipts[i].Status
+		}
+		for _, payment := range tx.Payments() {
+			if !containsAddress(hook.addresses, tx.From) && !containsAddress(hook.addresses, payment.To) {
+				continue
+			}
+			payload, err := json.Marshal(webhookPayload{
+				Webhook:       hook.ID,
+				Confirmations: hook.Confirmations,
+				Transfer: transferLog{
+					BlockNumber: block.Number(),
+					BlockHash:   block.Hash(),
+					TxHash:      tx.Hash(),
+					From:        tx.From,
+					To:          payment.To,
+					Amount:      payment.Amount,
+					Reference:   hex.EncodeToString(tx.Reference),
+					Status:      status,
+				},
+			})
+			if err != nil {
+				continue
+			}
+			id, err := randomID()
+			if err != nil {
+				continue
+			}
+			reg.put(deliveryPrefix, id, &delivery{
+				ID:          id,
+				WebhookID:   hook.ID,
+				Payload:     payload,
+				NextAttempt: time.Now(),
+			})
+		}
+	}
+}
+
+// containsAddress reports whether addr is one of addrs
+func containsAddress(addrs []ibft.Address, addr ibft.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverPending POSTs every delivery that is due. Webhooks are delivered
+// concurrently, so that a slow receiver does not delay the others, and the
+// deliveries of a webhook in order. Failed deliveries are retried with an
+// exponential backoff until webhookMaxAttempts is reached.
+func (reg *webhookRegistry) deliverPending() {
+	now := time.Now()
+	due := map[string][]*delivery{}
+	it := reg.db.NewIterator(util.BytesPrefix(deliveryPrefix), nil)
+	for it.Next() {
+		d := &delivery{}
+		if err := json.Unmarshal(it.Value(), d); err != nil {
+			continue
+		}
+		if !d.NextAttempt.After(now) {
+			due[d.WebhookID] = append(due[d.WebhookID], d)
+		}
+	}
+	it.Release()
+
+	var wg sync.WaitGroup
+	for id, deliveries := range due {
+		reg.mu.Lock()
+		hook, ok := reg.webhooks[id]
+		reg.mu.Unlock()
+		if !ok {
+			for _, d := range deliveries {
+				reg.delete(deliveryPrefix, d.ID)
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(hook *webhook, deliveries []*delivery) {
+			defer wg.Done()
+			for _, d := range deliveries {
+				reg.deliver(hook, d, now)
+			}
+		}(hook, deliveries)
+	}
+	wg.Wait()
+}
+
+// deliver POSTs a delivery, and schedules its next attempt when it fails
+func (reg *webhookRegistry) deliver(hook *webhook, d *delivery, now time.Time) {
+	if err := reg.post(hook, d.Payload); err == nil {
+		reg.delete(deliveryPrefix, d.ID)
+		return
+	}
+
+	d.Attempts++
+	if d.Attempts >= webhookMaxAttempts {
+		reg.delete(deliveryPrefix, d.ID)
+		return
+	}
+	backoff := webhookRetryBase << uint(d.Attempts-1)
+	if backoff > webhookRetryMax {
+		backoff = webhookRetryMax
+	}
+	d.NextAttempt = now.Add(backoff)
+	reg.put(deliveryPrefix, d.ID, d)
+}
+
+func (reg *webhookRegistry) post(hook *webhook, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader, signPayload(hook.Secret, payload))
+
+	resp, err := reg.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("webhook responded " + resp.Status)
+	}
+	return nil
+}
+
+// signPayload returns the hex encoded HMAC-SHA256 of a payload
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// run queues the transfers of the committed heads and delivers the queued
+// payloads until stop is closed
+func (reg *webhookRegistry) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(webhookPollPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case h := <-reg.heads:
+			reg.process(h.bc, h.number)
+		case <-ticker.C:
+			reg.deliverPending()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func openWebhookRegistry() (*webhookRegistry, error) {
+	if *webhookDataPath == "" {
+		return nil, nil
+	}
+	db, err := rawdb.InitDB(*webhookDataPath)
+	if err != nil {
+		return nil, err
+	}
+	return newWebhookRegistry(db)
+}
+
+func (ep *Endpoint) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if ep.webhooks == nil {
+		http.Error(w, errWebhooksDisabled.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(ep.webhooks.list())
+	case http.MethodPost:
+		hook := &webhook{}
+		if err := json.NewDecoder(r.Body).Decode(hook); err != nil {
+			http.Error(w, "invalid webhook: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		head := ep.Currency.BlockChain().CurrentBlock().Number().Uint64()
+		if err := ep.webhooks.register(hook, head); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+	case http.MethodDelete:
+		if !ep.webhooks.unregister(r.URL.Query().Get("id")) {
+			http.Error(w, "unknown webhook", http.StatusNotFound)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
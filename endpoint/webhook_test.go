@@ -0,0 +1,272 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/rawdb"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type testChain struct {
+	blocks []*types.Block
+}
+
+func (c *testChain) GetBlockByNumber(number uint64) *types.Block {
+	if number >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return c.blocks[number]
+}
+
+func (c *testChain) GetReceiptsByHash(hash ibft.Hash) types.Receipts {
+	for _, b := range c.blocks {
+		if b.Hash() == hash {
+			receipts := types.Receipts{}
+			for _, tx := range b.Transactions {
+				receipts = append(receipts, types.NewReceipt(tx.Hash(), types.ReceiptStatusSuccessful))
+			}
+			return receipts
+		}
+	}
+	return nil
+}
+
+func newTestChain(txs ...*types.Transaction) *testChain {
+	c := &testChain{}
+	for i := 0; i < 3; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Time: big.NewInt(0)}
+		if i > 0 {
+			header.ParentHash = c.blocks[i-1].Hash()
+		}
+		block := types.NewBlock(header, types.Transactions{})
+		if i == 1 {
+			block.Transactions = txs
+		}
+		c.blocks = append(c.blocks, block)
+	}
+	return c
+}
+
+func newTestRegistry() (*webhookRegistry, func()) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	if err != nil {
+		panic(err)
+	}
+	db, err := rawdb.InitDB(dir)
+	if err != nil {
+		panic(err)
+	}
+	reg, err := newWebhookRegistry(db)
+	if err != nil {
+		panic(err)
+	}
+	return reg, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func countDeliveries(reg *webhookRegistry) int {
+	n := 0
+	it := reg.db.NewIterator(util.BytesPrefix(deliveryPrefix), nil)
+	defer it.Release()
+	for it.Next() {
+		n++
+	}
+	return n
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	from, to := ibft.Address{1}, ibft.Address{2}
+	tx := types.NewTransaction(from, to, big.NewInt(42))
+	chain := newTestChain(tx)
+
+	received := make(chan webhookPayload, 1)
+	var secret string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(webhookSignatureHeader) != signPayload(secret, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		payload := webhookPayload{}
+		json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	reg, cleanup := newTestRegistry()
+	defer cleanup()
+
+	hook := &webhook{
+		URL:           server.URL,
+		Addresses:     []string{hex.EncodeToString(to.Bytes())},
+		Confirmations: 1,
+	}
+	if err := reg.register(hook, 0); err != nil {
+		t.Fatal(err)
+	}
+	secret = hook.Secret
+
+	// Block #1 holds the transfer but has no confirmation yet
+	reg.process(chain, 1)
+	if n := countDeliveries(reg); n != 0 {
+		t.Fatalf("expected no delivery before confirmation, got %d", n)
+	}
+
+	reg.process(chain, 2)
+	if n := countDeliveries(reg); n != 1 {
+		t.Fatalf("expected 1 queued delivery, got %d", n)
+	}
+
+	reg.deliverPending()
+	select {
+	case payload := <-received:
+		if payload.Transfer.TxHash != tx.Hash() || payload.Transfer.Status != types.ReceiptStatusSuccessful {
+			t.Fatalf("unexpected payload %+v", payload)
+		}
+	default:
+		t.Fatal("webhook was not called")
+	}
+	if n := countDeliveries(reg); n != 0 {
+		t.Fatalf("expected an empty queue after delivery, got %d", n)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	from, to := ibft.Address{1}, ibft.Address{2}
+	chain := newTestChain(types.NewTransaction(from, to, big.NewInt(42)))
+
+	reg, cleanup := newTestRegistry()
+	defer cleanup()
+
+	hook := &webhook{
+		URL:       server.URL,
+		Addresses: []string{hex.EncodeToString(from.Bytes())},
+	}
+	if err := reg.register(hook, 0); err != nil {
+		t.Fatal(err)
+	}
+	reg.process(chain, 1)
+	reg.deliverPending()
+
+	it := reg.db.NewIterator(util.BytesPrefix(deliveryPrefix), nil)
+	defer it.Release()
+	if !it.Next() {
+		t.Fatal("failed delivery was dropped")
+	}
+	d := &delivery{}
+	json.Unmarshal(it.Value(), d)
+	if d.Attempts != 1 || !d.NextAttempt.After(time.Now()) {
+		t.Fatalf("unexpected retry state %+v", d)
+	}
+}
+
+func TestWebhookWorker(t *testing.T) {
+	from, to := ibft.Address{1}, ibft.Address{2}
+	chain := newTestChain(types.NewTransaction(from, to, big.NewInt(42)))
+
+	// A receiver that never answers does not delay the others
+	stalled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stalled
+	}))
+	defer slow.Close()
+	defer close(stalled)
+	received := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer fast.Close()
+
+	reg, cleanup := newTestRegistry()
+	defer cleanup()
+	for _, url := range []string{slow.URL, fast.URL} {
+		if err := reg.register(&webhook{URL: url, Addresses: []string{hex.EncodeToString(to.Bytes())}}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Heads are handed to the worker, which queues and delivers the transfers
+	stop := make(chan struct{})
+	defer close(stop)
+	go reg.run(stop)
+	reg.commit(chain, 0)
+	reg.commit(chain, 1)
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestStopWaitsForWebhooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { *webhookDataPath = "" }()
+	*webhookDataPath = dir
+
+	called := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+		<-release
+	}))
+	defer receiver.Close()
+	defer releaseOnce.Do(func() { close(release) })
+
+	ep := New()
+	to := ibft.Address{2}
+	if err := ep.webhooks.register(&webhook{URL: receiver.URL, Addresses: []string{hex.EncodeToString(to.Bytes())}}, 0); err != nil {
+		t.Fatal(err)
+	}
+	addr := freeAddr()
+	go ep.Start(addr)
+	waitForServer(addr)
+	chain := newTestChain(types.NewTransaction(ibft.Address{1}, to, big.NewInt(42)))
+	ep.webhooks.commit(chain, 1)
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+
+	// The webhook database is closed once the running delivery is recorded
+	stopped := make(chan error, 1)
+	go func() { stopped <- ep.Stop(context.Background()) }()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned during a webhook delivery")
+	case <-time.After(100 * time.Millisecond):
+	}
+	releaseOnce.Do(func() { close(release) })
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return after the delivery")
+	}
+}
@@ -32,6 +32,14 @@ func main() {
 	var noDisco = flag.Bool("no-discovery", false, "disable dns peer discovery")
 	flag.Parse()
 
+	if flag.Arg(0) == "migrate-wallet" {
+		migrateWallet(*walletPath)
+		return
+	}
+	if flag.NArg() > 0 && runCommand(flag.Args(), *walletPath) {
+		return
+	}
+
 	logger.SetFlags(log.Lshortfile | log.Lmicroseconds)
 
 	wallet, err := wallet.New(*walletPath)
@@ -55,3 +63,15 @@ func main() {
 
 	currency.SyncAndStart(syncAddrs)
 }
+
+// migrateWallet converts a PEM wallet into an encrypted keystore
+func migrateWallet(path string) {
+	passphrase, err := wallet.Passphrase(true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := wallet.Migrate(path, passphrase); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Wallet at " + path + " is now encrypted")
+}
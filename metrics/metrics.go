@@ -0,0 +1,353 @@
+// Package metrics implements the few metric types exposed by a node and
+// renders them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRegistry holds the metrics registered by the NewRegistered helpers
+var DefaultRegistry = NewRegistry()
+
+// DefaultBuckets are the latency buckets, in seconds, used by histograms
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metric is a value that can be rendered in the Prometheus text format
+type Metric interface {
+	// Type returns the Prometheus type of the metric
+	Type() string
+	// Write renders the samples of the metric named name
+	Write(w io.Writer, name string)
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	value uint64
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add increments the counter by n
+func (c *Counter) Add(n uint64) { atomic.AddUint64(&c.value, n) }
+
+// Value returns the current value of the counter
+func (c *Counter) Value() uint64 { return atomic.LoadUint64(&c.value) }
+
+// Type implements Metric
+func (c *Counter) Type() string { return "counter" }
+
+// Write implements Metric
+func (c *Counter) Write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %d\n", name, c.Value())
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	bits uint64
+}
+
+// Set sets the gauge to v
+func (g *Gauge) Set(v float64) { atomic.StoreUint64(&g.bits, math.Float64bits(v)) }
+
+// Add adds d to the gauge
+func (g *Gauge) Add(d float64) {
+	for {
+		old := atomic.LoadUint64(&g.bits)
+		if atomic.CompareAndSwapUint64(&g.bits, old, math.Float64bits(math.Float64frombits(old)+d)) {
+			return
+		}
+	}
+}
+
+// Inc increments the gauge by one
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec decrements the gauge by one
+func (g *Gauge) Dec() { g.Add(-1) }
+
+// Value returns the current value of the gauge
+func (g *Gauge) Value() float64 { return math.Float64frombits(atomic.LoadUint64(&g.bits)) }
+
+// Type implements Metric
+func (g *Gauge) Type() string { return "gauge" }
+
+// Write implements Metric
+func (g *Gauge) Write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(g.Value()))
+}
+
+// GaugeFunc is a gauge whose value is computed when it is collected
+type GaugeFunc func() float64
+
+// Type implements Metric
+func (f GaugeFunc) Type() string { return "gauge" }
+
+// Write implements Metric
+func (f GaugeFunc) Write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(f()))
+}
+
+// CounterFunc is a counter whose value is computed when it is collected. The
+// function must return a monotonically increasing value.
+type CounterFunc func() float64
+
+// Type implements Metric
+func (f CounterFunc) Type() string { return "counter" }
+
+// Write implements Metric
+func (f CounterFunc) Write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(f()))
+}
+
+// CounterVec is a set of counters partitioned by the value of a label
+type CounterVec struct {
+	label    string
+	mu       sync.RWMutex
+	counters map[string]*Counter
+}
+
+// NewCounterVec returns an empty set of counters partitioned by label
+func NewCounterVec(label string) *CounterVec {
+	return &CounterVec{label: label, counters: make(map[string]*Counter)}
+}
+
+// With returns the counter associated to a label value, creating it if needed
+func (v *CounterVec) With(value string) *Counter {
+	v.mu.RLock()
+	c, ok := v.counters[value]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.counters[value]; !ok {
+		c = &Counter{}
+		v.counters[value] = c
+	}
+	return c
+}
+
+// Type implements Metric
+func (v *CounterVec) Type() string { return "counter" }
+
+// Write implements Metric
+func (v *CounterVec) Write(w io.Writer, name string) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, value := range sortedKeys(v.counters) {
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", name, v.label, value, v.counters[value].Value())
+	}
+}
+
+// Histogram counts observations in cumulative buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram returns a histogram with the given upper bounds
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// Observe records a value
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Type implements Metric
+func (h *Histogram) Type() string { return "histogram" }
+
+// Write implements Metric
+func (h *Histogram) Write(w io.Writer, name string) {
+	h.write(w, name, "")
+}
+
+func (h *Histogram) write(w io.Writer, name string, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	for i, bound := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", name, labels, sep, formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// HistogramVec is a set of histograms partitioned by the value of a label
+type HistogramVec struct {
+	label      string
+	buckets    []float64
+	mu         sync.RWMutex
+	histograms map[string]*Histogram
+}
+
+// NewHistogramVec returns an empty set of histograms partitioned by label
+func NewHistogramVec(label string, buckets []float64) *HistogramVec {
+	return &HistogramVec{label: label, buckets: buckets, histograms: make(map[string]*Histogram)}
+}
+
+// With returns the histogram associated to a label value, creating it if
+// needed
+func (v *HistogramVec) With(value string) *Histogram {
+	v.mu.RLock()
+	h, ok := v.histograms[value]
+	v.mu.RUnlock()
+	if ok {
+		return h
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok = v.histograms[value]; !ok {
+		h = NewHistogram(v.buckets)
+		v.histograms[value] = h
+	}
+	return h
+}
+
+// Type implements Metric
+func (v *HistogramVec) Type() string { return "histogram" }
+
+// Write implements Metric
+func (v *HistogramVec) Write(w io.Writer, name string) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, value := range sortedKeys(v.histograms) {
+		v.histograms[value].write(w, name, fmt.Sprintf("%s=%q", v.label, value))
+	}
+}
+
+type entry struct {
+	help   string
+	metric Metric
+}
+
+// Registry is a named set of metrics
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]entry
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]entry)}
+}
+
+// Register adds a metric to the registry. A metric registered under an
+// existing name replaces the previous one.
+func (r *Registry) Register(name, help string, m Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics[name] = entry{help: help, metric: m}
+}
+
+// Write renders every metric of the registry sorted by name
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, name := range sortedKeys(r.metrics) {
+		e := r.metrics[name]
+		fmt.Fprintf(w, "# HELP %s %s\n", name, strings.Replace(e.help, "\n", " ", -1))
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, e.metric.Type())
+		e.metric.Write(w, name)
+	}
+}
+
+// NewRegisteredCounter creates a counter and registers it in the default
+// registry
+func NewRegisteredCounter(name, help string) *Counter {
+	c := &Counter{}
+	DefaultRegistry.Register(name, help, c)
+	return c
+}
+
+// NewRegisteredGauge creates a gauge and registers it in the default registry
+func NewRegisteredGauge(name, help string) *Gauge {
+	g := &Gauge{}
+	DefaultRegistry.Register(name, help, g)
+	return g
+}
+
+// NewRegisteredGaugeFunc registers a computed gauge in the default registry
+func NewRegisteredGaugeFunc(name, help string, f func() float64) {
+	DefaultRegistry.Register(name, help, GaugeFunc(f))
+}
+
+// NewRegisteredCounterFunc registers a computed counter in the default
+// registry
+func NewRegisteredCounterFunc(name, help string, f func() float64) {
+	DefaultRegistry.Register(name, help, CounterFunc(f))
+}
+
+// NewRegisteredCounterVec creates a labeled counter set and registers it in
+// the default registry
+func NewRegisteredCounterVec(name, help, label string) *CounterVec {
+	v := NewCounterVec(label)
+	DefaultRegistry.Register(name, help, v)
+	return v
+}
+
+// NewRegisteredHistogramVec creates a labeled histogram set and registers it
+// in the default registry
+func NewRegisteredHistogramVec(name, help, label string, buckets []float64) *HistogramVec {
+	v := NewHistogramVec(label, buckets)
+	DefaultRegistry.Register(name, help, v)
+	return v
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch m := m.(type) {
+	case map[string]*Counter:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*Histogram:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]entry:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
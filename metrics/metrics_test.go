@@ -0,0 +1,53 @@
+package metrics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-slash-currency/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	r := metrics.NewRegistry()
+
+	c := &metrics.Counter{}
+	c.Add(3)
+	r.Register("test_total", "A counter.", c)
+
+	v := metrics.NewCounterVec("remote")
+	v.With("a:1").Inc()
+	r.Register("test_remote_total", "A labeled counter.", v)
+
+	r.Register("test_gauge", "A gauge.", metrics.GaugeFunc(func() float64 { return 1.5 }))
+	r.Register("test_computed_total", "A computed counter.", metrics.CounterFunc(func() float64 { return 7 }))
+
+	h := metrics.NewHistogram([]float64{1, 2})
+	h.Observe(0.5)
+	h.Observe(1.5)
+	h.Observe(3)
+	r.Register("test_seconds", "A histogram.", h)
+
+	out := &bytes.Buffer{}
+	r.Write(out)
+
+	expected := []string{
+		"# TYPE test_total counter",
+		"test_total 3",
+		`test_remote_total{remote="a:1"} 1`,
+		"# HELP test_gauge A gauge.",
+		"test_gauge 1.5",
+		"# TYPE test_computed_total counter",
+		"test_computed_total 7",
+		`test_seconds_bucket{le="1"} 1`,
+		`test_seconds_bucket{le="2"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		"test_seconds_sum 5",
+		"test_seconds_count 3",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("missing line %q in:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// DatabaseStat is the number and size of the entries of a kind of data
+type DatabaseStat struct {
+	Name  string
+	Count uint64
+	Size  uint64 // keys and values, in bytes
+}
+
+// InspectDatabase walks the whole database and reports the number and size of
+// its entries per key prefix of the schema.
+func InspectDatabase(db *leveldb.DB) ([]*DatabaseStat, error) {
+	var (
+		blocks      = &DatabaseStat{Name: "Blocks"}
+		blockHashes = &DatabaseStat{Name: "Canonical hashes"}
+		numbers     = &DatabaseStat{Name: "Block numbers"}
+		receipts    = &DatabaseStat{Name: "Receipts"}
+		txLookups   = &DatabaseStat{Name: "Transaction index"}
+		addressTxs  = &DatabaseStat{Name: "Address index"}
+		referenceTx = &DatabaseStat{Name: "Reference index"}
+		stateDiffs  = &DatabaseStat{Name: "State diffs"}
+		demurrage   = &DatabaseStat{Name: "Demurrage charges"}
+		charges     = &DatabaseStat{Name: "Demurrage index"}
+		supply      = &DatabaseStat{Name: "Supply stats"}
+		mints       = &DatabaseStat{Name: "Committed mints"}
+		checkpoints = &DatabaseStat{Name: "State checkpoints"}
+		metadata    = &DatabaseStat{Name: "Metadata"}
+		unknown     = &DatabaseStat{Name: "Unknown"}
+	)
+	hashLen := len(blockHashKey(0))
+
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		var stat *DatabaseStat
+		switch {
+		case bytes.HasPrefix(key, blockPrefix) && len(key) == hashLen && bytes.HasSuffix(key, blockHashSuffix):
+			stat = blockHashes
+		case bytes.HasPrefix(key, blockPrefix):
+			stat = blocks
+		case bytes.HasPrefix(key, blockNumberPrefix):
+			stat = numbers
+		case bytes.HasPrefix(key, blockReceiptsPrefix):
+			stat = receipts
+		case bytes.HasPrefix(key, txLookupPrefix):
+			stat = txLookups
+		case bytes.HasPrefix(key, addressTxPrefix):
+			stat = addressTxs
+		case bytes.HasPrefix(key, referenceTxPrefix):
+			stat = referenceTx
+		case bytes.HasPrefix(key, stateDiffPrefix):
+			stat = stateDiffs
+		case bytes.HasPrefix(key, demurragePrefix):
+			stat = demurrage
+		case bytes.HasPrefix(key, accountChargePrefix):
+			stat = charges
+		case bytes.HasPrefix(key, supplyStatsPrefix):
+			stat = supply
+		case bytes.HasPrefix(key, blockMintsPrefix):
+			stat = mints
+		case bytes.HasPrefix(key, checkpointPrefix):
+			stat = checkpoints
+		case bytes.Equal(key, headBlockKey), bytes.Equal(key, stateSnapshotKey), bytes.Equal(key, genesisKey), bytes.Equal(key, probeKey):
+			stat = metadata
+		default:
+			stat = unknown
+		}
+		stat.Count++
+		stat.Size += uint64(len(key) + len(it.Value()))
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return []*DatabaseStat{blocks, blockHashes, numbers, receipts, txLookups, addressTxs, referenceTx, stateDiffs, demurrage, charges, supply, mints, checkpoints, metadata, unknown}, nil
+}
@@ -5,11 +5,13 @@ import (
 	"bitbucket.org/ventureslash/go-slash-currency/types"
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"log"
 )
 
@@ -31,6 +33,15 @@ func InitDB(file string) (*leveldb.DB, error) {
 	return db, nil
 }
 
+// CheckWritable writes and deletes a probe key to verify that the database
+// accepts writes.
+func CheckWritable(db *leveldb.DB) error {
+	if err := db.Put(probeKey, []byte{1}, nil); err != nil {
+		return err
+	}
+	return db.Delete(probeKey, nil)
+}
+
 // ReadBlockHash retrieves the hash assigned to a block number.
 func ReadBlockHash(db *leveldb.DB, number uint64) ibft.Hash {
 	data, _ := db.Get(blockHashKey(number), nil)
@@ -103,30 +114,36 @@ func ReadBlock(db *leveldb.DB, hash ibft.Hash, number uint64) *types.Block {
 }
 
 // WriteBlockRLP stores an RLP encoded block into the database.
-func WriteBlockRLP(db *leveldb.DB, hash ibft.Hash, number uint64, rlp rlp.RawValue) {
+func WriteBlockRLP(db *leveldb.DB, hash ibft.Hash, number uint64, rlp rlp.RawValue) error {
 	// Write the hash -> number mapping
 	var encoded = encodeBlockNumber(number)
 	key := blockNumberKey(hash)
 	if err := db.Put(key, encoded, nil); err != nil {
-		log.Println("Failed to store hash to number mapping", "err", err)
+		return fmt.Errorf("failed to store hash to number mapping: %v", err)
 	}
 	if err := db.Put(blockKey(number, hash), rlp, nil); err != nil {
-		log.Println("Failed to store block body", "err", err)
+		return fmt.Errorf("failed to store block body: %v", err)
 	}
+	return nil
 }
 
 // WriteBlock serializes a block into the database, header and body separately.
-func WriteBlock(db *leveldb.DB, block *types.Block) {
+func WriteBlock(db *leveldb.DB, block *types.Block) error {
 	data, err := rlp.EncodeToBytes(block)
 	if err != nil {
-		log.Println("Failed to RLP encode body", "err", err)
+		return fmt.Errorf("failed to RLP encode body: %v", err)
 	}
-	WriteBlockRLP(db, block.Hash(), block.Number().Uint64(), data)
+	return WriteBlockRLP(db, block.Hash(), block.Number().Uint64(), data)
 }
 
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db *leveldb.DB, hash ibft.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteStateDiff(db, hash, number)
+	DeleteDemurrageCharges(db, hash, number)
+	DeleteSupplyStats(db, hash, number)
+	DeleteBlockMints(db, hash, number)
+	DeleteStateCheckpoint(db, hash, number)
 	if err := db.Delete(blockNumberKey(hash), nil); err != nil {
 		log.Println("Failed to delete hash to number mapping", "err", err)
 	}
@@ -177,3 +194,390 @@ func DeleteReceipts(db *leveldb.DB, hash ibft.Hash, number uint64) {
 		log.Println("Failed to delete block receipts", "err", err)
 	}
 }
+
+// HasStateDiff verifies the existence of the state diff of a block.
+func HasStateDiff(db *leveldb.DB, hash ibft.Hash, number uint64) bool {
+	if has, err := db.Has(stateDiffKey(number, hash), nil); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadStateDiff retrieves the accounts modified by a block. The second return
+// value is false if the diff is not stored in the database.
+func ReadStateDiff(db *leveldb.DB, hash ibft.Hash, number uint64) (types.StateDiff, bool) {
+	data, err := db.Get(stateDiffKey(number, hash), nil)
+	if err != nil {
+		return nil, false
+	}
+	diff := types.StateDiff{}
+	if err := rlp.DecodeBytes(data, &diff); err != nil {
+		log.Println("Invalid state diff RLP", "hash", hash, "err", err)
+		return nil, false
+	}
+	return diff, true
+}
+
+// WriteStateDiff stores the accounts modified by a block.
+func WriteStateDiff(db *leveldb.DB, hash ibft.Hash, number uint64, diff types.StateDiff) {
+	bytes, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		log.Println("Failed to encode state diff", "err", err)
+	}
+	if err := db.Put(stateDiffKey(number, hash), bytes, nil); err != nil {
+		log.Println("Failed to store state diff", "err", err)
+	}
+}
+
+// DeleteStateDiff removes the state diff of a block.
+func DeleteStateDiff(db *leveldb.DB, hash ibft.Hash, number uint64) {
+	if err := db.Delete(stateDiffKey(number, hash), nil); err != nil {
+		log.Println("Failed to delete state diff", "err", err)
+	}
+}
+
+// ReadStateSnapshot retrieves the oldest full state kept by a pruning node.
+func ReadStateSnapshot(db *leveldb.DB) *types.StateSnapshot {
+	data, _ := db.Get(stateSnapshotKey, nil)
+	if len(data) == 0 {
+		return nil
+	}
+	snapshot := &types.StateSnapshot{}
+	if err := rlp.DecodeBytes(data, snapshot); err != nil {
+		log.Println("Invalid state snapshot RLP", "err", err)
+		return nil
+	}
+	return snapshot
+}
+
+// WriteStateSnapshot stores the oldest full state kept by a pruning node.
+func WriteStateSnapshot(db *leveldb.DB, snapshot *types.StateSnapshot) {
+	bytes, err := rlp.EncodeToBytes(snapshot)
+	if err != nil {
+		log.Println("Failed to encode state snapshot", "err", err)
+	}
+	if err := db.Put(stateSnapshotKey, bytes, nil); err != nil {
+		log.Println("Failed to store state snapshot", "err", err)
+	}
+}
+
+// DeleteStateSnapshot removes the stored state snapshot.
+func DeleteStateSnapshot(db *leveldb.DB) {
+	if err := db.Delete(stateSnapshotKey, nil); err != nil {
+		log.Println("Failed to delete state snapshot", "err", err)
+	}
+}
+
+// HasStateCheckpoint verifies the existence of the state checkpoint of a
+// block.
+func HasStateCheckpoint(db *leveldb.DB, hash ibft.Hash, number uint64) bool {
+	if has, err := db.Has(checkpointKey(number, hash), nil); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadStateCheckpoint retrieves the full state stored at a block, or nil if
+// the block has no checkpoint.
+func ReadStateCheckpoint(db *leveldb.DB, hash ibft.Hash, number uint64) *types.StateSnapshot {
+	data, _ := db.Get(checkpointKey(number, hash), nil)
+	if len(data) == 0 {
+		return nil
+	}
+	checkpoint := &types.StateSnapshot{}
+	if err := rlp.DecodeBytes(data, checkpoint); err != nil {
+		log.Println("Invalid state checkpoint RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return checkpoint
+}
+
+// WriteStateCheckpoint stores the full state at a block.
+func WriteStateCheckpoint(db *leveldb.DB, hash ibft.Hash, number uint64, checkpoint *types.StateSnapshot) {
+	bytes, err := rlp.EncodeToBytes(checkpoint)
+	if err != nil {
+		log.Println("Failed to encode state checkpoint", "err", err)
+	}
+	if err := db.Put(checkpointKey(number, hash), bytes, nil); err != nil {
+		log.Println("Failed to store state checkpoint", "err", err)
+	}
+}
+
+// DeleteStateCheckpoint removes the state checkpoint of a block.
+func DeleteStateCheckpoint(db *leveldb.DB, hash ibft.Hash, number uint64) {
+	if err := db.Delete(checkpointKey(number, hash), nil); err != nil {
+		log.Println("Failed to delete state checkpoint", "err", err)
+	}
+}
+
+// ReadGenesis retrieves the JSON genesis configuration of the chain.
+func ReadGenesis(db *leveldb.DB) []byte {
+	data, _ := db.Get(genesisKey, nil)
+	return data
+}
+
+// WriteGenesis stores the JSON genesis configuration of the chain.
+func WriteGenesis(db *leveldb.DB, data []byte) {
+	if err := db.Put(genesisKey, data, nil); err != nil {
+		log.Println("Failed to store genesis configuration", "err", err)
+	}
+}
+
+// ReadSupplyStats retrieves the accounting of the money supply at a block.
+func ReadSupplyStats(db *leveldb.DB, hash ibft.Hash, number uint64) *types.SupplyStats {
+	data, _ := db.Get(supplyStatsKey(number, hash), nil)
+	if len(data) == 0 {
+		return nil
+	}
+	stats := &types.SupplyStats{}
+	if err := rlp.DecodeBytes(data, stats); err != nil {
+		log.Println("Invalid supply stats RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return stats
+}
+
+// WriteSupplyStats stores the accounting of the money supply at a block.
+func WriteSupplyStats(db *leveldb.DB, hash ibft.Hash, number uint64, stats *types.SupplyStats) {
+	bytes, err := rlp.EncodeToBytes(stats)
+	if err != nil {
+		log.Println("Failed to encode supply stats", "err", err)
+	}
+	if err := db.Put(supplyStatsKey(number, hash), bytes, nil); err != nil {
+		log.Println("Failed to store supply stats", "err", err)
+	}
+}
+
+// DeleteSupplyStats removes the accounting of the money supply at a block.
+func DeleteSupplyStats(db *leveldb.DB, hash ibft.Hash, number uint64) {
+	if err := db.Delete(supplyStatsKey(number, hash), nil); err != nil {
+		log.Println("Failed to delete supply stats", "err", err)
+	}
+}
+
+// ReadBlockMints retrieves the IDs of the mints committed by a block.
+func ReadBlockMints(db *leveldb.DB, hash ibft.Hash, number uint64) []ibft.Hash {
+	data, _ := db.Get(blockMintsKey(number, hash), nil)
+	if len(data) == 0 {
+		return nil
+	}
+	ids := []ibft.Hash{}
+	if err := rlp.DecodeBytes(data, &ids); err != nil {
+		log.Println("Invalid block mints RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return ids
+}
+
+// WriteBlockMints stores the IDs of the mints committed by a block.
+func WriteBlockMints(db *leveldb.DB, hash ibft.Hash, number uint64, ids []ibft.Hash) {
+	bytes, err := rlp.EncodeToBytes(ids)
+	if err != nil {
+		log.Println("Failed to encode block mints", "err", err)
+	}
+	if err := db.Put(blockMintsKey(number, hash), bytes, nil); err != nil {
+		log.Println("Failed to store block mints", "err", err)
+	}
+}
+
+// DeleteBlockMints removes the IDs of the mints committed by a block.
+func DeleteBlockMints(db *leveldb.DB, hash ibft.Hash, number uint64) {
+	if err := db.Delete(blockMintsKey(number, hash), nil); err != nil {
+		log.Println("Failed to delete block mints", "err", err)
+	}
+}
+
+// HasDemurrageCharges verifies the existence of the demurrage charges of a
+// block.
+func HasDemurrageCharges(db *leveldb.DB, hash ibft.Hash, number uint64) bool {
+	if has, err := db.Has(demurrageKey(number, hash), nil); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadDemurrageCharges retrieves the demurrage charged by a block.
+func ReadDemurrageCharges(db *leveldb.DB, hash ibft.Hash, number uint64) types.DemurrageCharges {
+	data, _ := db.Get(demurrageKey(number, hash), nil)
+	if len(data) == 0 {
+		return nil
+	}
+	charges := types.DemurrageCharges{}
+	if err := rlp.DecodeBytes(data, &charges); err != nil {
+		log.Println("Invalid demurrage charges RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return charges
+}
+
+// WriteDemurrageCharges stores the demurrage charged by a block, indexed by
+// block and by account.
+func WriteDemurrageCharges(db *leveldb.DB, hash ibft.Hash, number uint64, charges types.DemurrageCharges) {
+	bytes, err := rlp.EncodeToBytes(charges)
+	if err != nil {
+		log.Println("Failed to encode demurrage charges", "err", err)
+	}
+	if err := db.Put(demurrageKey(number, hash), bytes, nil); err != nil {
+		log.Println("Failed to store demurrage charges", "err", err)
+	}
+	for _, charge := range charges {
+		data, err := rlp.EncodeToBytes(charge)
+		if err != nil {
+			log.Println("Failed to encode demurrage charge", "err", err)
+			continue
+		}
+		if err := db.Put(accountChargeKey(charge.Address, number), data, nil); err != nil {
+			log.Println("Failed to store account demurrage charge", "err", err)
+		}
+	}
+}
+
+// DeleteDemurrageCharges removes the demurrage charged by a block and its
+// account index entries.
+func DeleteDemurrageCharges(db *leveldb.DB, hash ibft.Hash, number uint64) {
+	for _, charge := range ReadDemurrageCharges(db, hash, number) {
+		if err := db.Delete(accountChargeKey(charge.Address, number), nil); err != nil {
+			log.Println("Failed to delete account demurrage charge", "err", err)
+		}
+	}
+	if err := db.Delete(demurrageKey(number, hash), nil); err != nil {
+		log.Println("Failed to delete demurrage charges", "err", err)
+	}
+}
+
+// ReadAccountCharges retrieves the demurrage charged to an account, most
+// recent first. At most limit charges are returned, skipping the first offset
+// ones.
+func ReadAccountCharges(db *leveldb.DB, addr ibft.Address, offset int, limit int) types.DemurrageCharges {
+	charges := types.DemurrageCharges{}
+	it := db.NewIterator(util.BytesPrefix(accountChargePrefixKey(addr)), nil)
+	defer it.Release()
+	for ok := it.Last(); ok && len(charges) < limit; ok = it.Prev() {
+		if offset > 0 {
+			offset--
+			continue
+		}
+		charge := &types.DemurrageCharge{}
+		if err := rlp.DecodeBytes(it.Value(), charge); err != nil {
+			log.Println("Invalid account demurrage charge RLP", "err", err)
+			continue
+		}
+		charges = append(charges, charge)
+	}
+	return charges
+}
+
+// ReadTxLookupEntry retrieves the positional metadata associated with a
+// transaction hash.
+func ReadTxLookupEntry(db *leveldb.DB, hash ibft.Hash) *TxLookupEntry {
+	data, _ := db.Get(txLookupKey(hash), nil)
+	if len(data) == 0 {
+		return nil
+	}
+	entry := &TxLookupEntry{}
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Println("Invalid transaction lookup entry RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return entry
+}
+
+// WriteTxLookupEntries stores the positional metadata of every transaction of
+// a block, indexed by transaction hash and by the addresses involved.
+func WriteTxLookupEntries(db *leveldb.DB, block *types.Block) {
+	number := block.Number().Uint64()
+	for i, tx := range block.Transactions {
+		entry := TxLookupEntry{
+			BlockHash:  block.Hash(),
+			BlockIndex: number,
+			Index:      uint64(i),
+		}
+		data, err := rlp.EncodeToBytes(entry)
+		if err != nil {
+			log.Println("Failed to encode transaction lookup entry", "err", err)
+			continue
+		}
+		if err := db.Put(txLookupKey(tx.Hash()), data, nil); err != nil {
+			log.Println("Failed to store transaction lookup entry", "err", err)
+		}
+		for _, addr := range txAddresses(tx) {
+			if err := db.Put(addressTxKey(addr, number, uint64(i)), data, nil); err != nil {
+				log.Println("Failed to store address transaction entry", "err", err)
+			}
+			if len(tx.Reference) == 0 {
+				continue
+			}
+			if err := db.Put(referenceTxKey(addr, tx.Reference, number, uint64(i)), data, nil); err != nil {
+				log.Println("Failed to store reference transaction entry", "err", err)
+			}
+		}
+	}
+}
+
+// txAddresses returns the sender of tx and the recipients of its payments
+func txAddresses(tx *types.Transaction) []ibft.Address {
+	addrs := []ibft.Address{tx.From}
+	for _, payment := range tx.Payments() {
+		addrs = append(addrs, payment.To)
+	}
+	return addrs
+}
+
+// DeleteTxLookupEntries removes the positional metadata of every transaction
+// of a block.
+func DeleteTxLookupEntries(db *leveldb.DB, block *types.Block) {
+	number := block.Number().Uint64()
+	for i, tx := range block.Transactions {
+		if entry := ReadTxLookupEntry(db, tx.Hash()); entry != nil && entry.BlockHash == block.Hash() {
+			if err := db.Delete(txLookupKey(tx.Hash()), nil); err != nil {
+				log.Println("Failed to delete transaction lookup entry", "err", err)
+			}
+		}
+		for _, addr := range txAddresses(tx) {
+			if err := db.Delete(addressTxKey(addr, number, uint64(i)), nil); err != nil {
+				log.Println("Failed to delete address transaction entry", "err", err)
+			}
+			if len(tx.Reference) == 0 {
+				continue
+			}
+			if err := db.Delete(referenceTxKey(addr, tx.Reference, number, uint64(i)), nil); err != nil {
+				log.Println("Failed to delete reference transaction entry", "err", err)
+			}
+		}
+	}
+}
+
+// ReadAddressTxEntries retrieves the positional metadata of the transactions
+// sent or received by an address, most recent first. At most limit entries
+// are returned, skipping the first offset ones.
+func ReadAddressTxEntries(db *leveldb.DB, addr ibft.Address, offset int, limit int) []*TxLookupEntry {
+	return readTxEntries(db, addressTxPrefixKey(addr), offset, limit)
+}
+
+// ReadReferenceTxEntries retrieves the positional metadata of the payments
+// carrying reference sent or received by an address, most recent first. At
+// most limit entries are returned, skipping the first offset ones.
+func ReadReferenceTxEntries(db *leveldb.DB, addr ibft.Address, reference []byte, offset int, limit int) []*TxLookupEntry {
+	return readTxEntries(db, referenceTxPrefixKey(addr, reference), offset, limit)
+}
+
+// readTxEntries reads the transaction lookup entries stored under prefix, in
+// the reverse order of their keys
+func readTxEntries(db *leveldb.DB, prefix []byte, offset int, limit int) []*TxLookupEntry {
+	entries := []*TxLookupEntry{}
+	it := db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+	for ok := it.Last(); ok && len(entries) < limit; ok = it.Prev() {
+		if offset > 0 {
+			offset--
+			continue
+		}
+		entry := &TxLookupEntry{}
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil {
+			log.Println("Invalid transaction entry RLP", "err", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
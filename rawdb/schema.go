@@ -4,6 +4,8 @@ package rawdb
 import (
 	"bitbucket.org/ventureslash/go-ibft"
 	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // The fields below define the low level database schema prefixing.
@@ -15,6 +17,21 @@ var (
 	blockNumberPrefix   = []byte("H") // blockNumberPrefix + hash -> num (uint64 big endian)
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	txLookupPrefix      = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	stateDiffPrefix     = []byte("s") // stateDiffPrefix + num (uint64 big endian) + hash -> accounts modified by the block
+	addressTxPrefix     = []byte("a") // addressTxPrefix + address + num (uint64 big endian) + index (uint64 big endian) -> transaction lookup metadata
+	demurragePrefix     = []byte("d") // demurragePrefix + num (uint64 big endian) + hash -> demurrage charged by the block
+	accountChargePrefix = []byte("c") // accountChargePrefix + address + num (uint64 big endian) -> demurrage charged to the account
+	supplyStatsPrefix   = []byte("m") // supplyStatsPrefix + num (uint64 big endian) + hash -> money supply accounting at the block
+	blockMintsPrefix    = []byte("i") // blockMintsPrefix + num (uint64 big endian) + hash -> IDs of the mints committed by the block
+	referenceTxPrefix   = []byte("f") // referenceTxPrefix + address + keccak256(reference) + num (uint64 big endian) + index (uint64 big endian) -> transaction lookup metadata
+	checkpointPrefix    = []byte("k") // checkpointPrefix + num (uint64 big endian) + hash -> full state at the block
+
+	// stateSnapshotKey tracks the oldest state kept once older diffs are pruned.
+	stateSnapshotKey = []byte("StateSnapshot")
+	// genesisKey stores the JSON genesis configuration the chain was created with.
+	genesisKey = []byte("Genesis")
+	// probeKey is written and deleted to check that the database is writable.
+	probeKey = []byte("Probe")
 )
 
 // TxLookupEntry is a positional metadata to help looking up the data content of
@@ -52,8 +69,63 @@ func blockReceiptsKey(number uint64, hash ibft.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
-// TODO: tx index (advanced)
+// stateDiffKey = stateDiffPrefix + num (uint64 big endian) + hash
+func stateDiffKey(number uint64, hash ibft.Hash) []byte {
+	return append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash ibft.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
 }
+
+// addressTxPrefixKey = addressTxPrefix + address
+func addressTxPrefixKey(addr ibft.Address) []byte {
+	return append(append([]byte{}, addressTxPrefix...), addr.Bytes()...)
+}
+
+// addressTxKey = addressTxPrefix + address + num (uint64 big endian) + index (uint64 big endian)
+func addressTxKey(addr ibft.Address, number uint64, index uint64) []byte {
+	return append(append(addressTxPrefixKey(addr), encodeBlockNumber(number)...), encodeBlockNumber(index)...)
+}
+
+// referenceTxPrefixKey = referenceTxPrefix + address + keccak256(reference)
+func referenceTxPrefixKey(addr ibft.Address, reference []byte) []byte {
+	key := append(append([]byte{}, referenceTxPrefix...), addr.Bytes()...)
+	return append(key, crypto.Keccak256(reference)...)
+}
+
+// referenceTxKey = referenceTxPrefix + address + keccak256(reference) + num (uint64 big endian) + index (uint64 big endian)
+func referenceTxKey(addr ibft.Address, reference []byte, number uint64, index uint64) []byte {
+	return append(append(referenceTxPrefixKey(addr, reference), encodeBlockNumber(number)...), encodeBlockNumber(index)...)
+}
+
+// demurrageKey = demurragePrefix + num (uint64 big endian) + hash
+func demurrageKey(number uint64, hash ibft.Hash) []byte {
+	return append(append(demurragePrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// accountChargePrefixKey = accountChargePrefix + address
+func accountChargePrefixKey(addr ibft.Address) []byte {
+	return append(append([]byte{}, accountChargePrefix...), addr.Bytes()...)
+}
+
+// accountChargeKey = accountChargePrefix + address + num (uint64 big endian)
+func accountChargeKey(addr ibft.Address, number uint64) []byte {
+	return append(accountChargePrefixKey(addr), encodeBlockNumber(number)...)
+}
+
+// blockMintsKey = blockMintsPrefix + num (uint64 big endian) + hash
+func blockMintsKey(number uint64, hash ibft.Hash) []byte {
+	return append(append(blockMintsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// checkpointKey = checkpointPrefix + num (uint64 big endian) + hash
+func checkpointKey(number uint64, hash ibft.Hash) []byte {
+	return append(append(checkpointPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// supplyStatsKey = supplyStatsPrefix + num (uint64 big endian) + hash
+func supplyStatsKey(number uint64, hash ibft.Hash) []byte {
+	return append(append(supplyStatsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
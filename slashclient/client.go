@@ -0,0 +1,266 @@
+// Package slashclient is a typed Go client of the endpoint of a slash
+// currency node. The routes it calls are described by the /openapi.json
+// document served by the node.
+package slashclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+// ErrNotFound is returned when the requested block, transaction or receipt is
+// unknown to the node
+var ErrNotFound = errors.New("not found")
+
+// Error is an error answered by the node
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("endpoint error %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the routes of a node endpoint
+type Client struct {
+	url    string
+	apiKey string
+	http   *http.Client
+}
+
+// New returns a client of the endpoint at url, e.g. "http://localhost:3000"
+func New(url string) *Client {
+	return &Client{
+		url:  strings.TrimSuffix(url, "/"),
+		http: http.DefaultClient,
+	}
+}
+
+// WithAPIKey returns a copy of the client authenticating with the given key
+func (c *Client) WithAPIKey(key string) *Client {
+	cpy := *c
+	cpy.apiKey = key
+	return &cpy
+}
+
+// WithHTTPClient returns a copy of the client sending its requests, and
+// dialing its websockets, with the given http client
+func (c *Client) WithHTTPClient(client *http.Client) *Client {
+	cpy := *c
+	cpy.http = client
+	return &cpy
+}
+
+func (c *Client) do(ctx context.Context, method string, route string, query url.Values, body interface{}, result interface{}) error {
+	u := c.url + route
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Balance returns the current balance of an account
+func (c *Client) Balance(ctx context.Context, addr ibft.Address) (*big.Int, error) {
+	return c.balance(ctx, url.Values{"account": {hex.EncodeToString(addr.Bytes())}})
+}
+
+// BalanceAt returns the balance of an account at the end of a block
+func (c *Client) BalanceAt(ctx context.Context, addr ibft.Address, number uint64) (*big.Int, error) {
+	return c.balance(ctx, url.Values{
+		"account": {hex.EncodeToString(addr.Bytes())},
+		"block":   {strconv.FormatUint(number, 10)},
+	})
+}
+
+func (c *Client) balance(ctx context.Context, query url.Values) (*big.Int, error) {
+	result := struct {
+		Balance uint64 `json:"balance"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, "/balance", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(result.Balance), nil
+}
+
+// Account returns the balance of an account and its latest transactions,
+// newest first
+func (c *Client) Account(ctx context.Context, addr ibft.Address, offset int, count int) (*Account, error) {
+	account := &Account{}
+	err := c.do(ctx, http.MethodGet, "/address", url.Values{
+		"account": {hex.EncodeToString(addr.Bytes())},
+		"offset":  {strconv.Itoa(offset)},
+		"count":   {strconv.Itoa(count)},
+	}, nil, account)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// PaymentsByReference returns the payments carrying reference sent or
+// received by an account, most recent first
+func (c *Client) PaymentsByReference(ctx context.Context, addr ibft.Address, reference []byte, offset int, count int) ([]*Transaction, error) {
+	txs := []*Transaction{}
+	err := c.do(ctx, http.MethodGet, "/accounts/"+hex.EncodeToString(addr.Bytes())+"/transactions", url.Values{
+		"reference": {hex.EncodeToString(reference)},
+		"offset":    {strconv.Itoa(offset)},
+		"count":     {strconv.Itoa(count)},
+	}, nil, &txs)
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// HeadBlock returns the current block with its transactions
+func (c *Client) HeadBlock(ctx context.Context) (*Block, error) {
+	return c.block(ctx, nil)
+}
+
+// BlockByNumber returns a block with its transactions
+func (c *Client) BlockByNumber(ctx context.Context, number uint64) (*Block, error) {
+	return c.block(ctx, url.Values{"number": {strconv.FormatUint(number, 10)}})
+}
+
+// BlockByHash returns a block with its transactions
+func (c *Client) BlockByHash(ctx context.Context, hash ibft.Hash) (*Block, error) {
+	return c.block(ctx, url.Values{"hash": {hex.EncodeToString(hash.Bytes())}})
+}
+
+func (c *Client) block(ctx context.Context, query url.Values) (*Block, error) {
+	block := &Block{}
+	if err := c.do(ctx, http.MethodGet, "/block", query, nil, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Blocks returns at most count blocks preceding block number before, newest
+// first. Their transactions are not included.
+func (c *Client) Blocks(ctx context.Context, before uint64, count int) ([]*Block, error) {
+	blocks := []*Block{}
+	err := c.do(ctx, http.MethodGet, "/blocks", url.Values{
+		"before": {strconv.FormatUint(before, 10)},
+		"count":  {strconv.Itoa(count)},
+	}, nil, &blocks)
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// Transaction returns a committed or pending transaction
+func (c *Client) Transaction(ctx context.Context, hash ibft.Hash) (*Transaction, error) {
+	tx := &Transaction{}
+	err := c.do(ctx, http.MethodGet, "/tx", url.Values{"hash": {hex.EncodeToString(hash.Bytes())}}, nil, tx)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// PendingTransactions returns the transactions waiting to be included in a
+// block
+func (c *Client) PendingTransactions(ctx context.Context) ([]*Transaction, error) {
+	txs := []*Transaction{}
+	if err := c.do(ctx, http.MethodGet, "/pending", nil, nil, &txs); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// Receipt returns the receipt of a committed transaction. ErrNotFound is
+// returned while the transaction is pending.
+func (c *Client) Receipt(ctx context.Context, hash ibft.Hash) (*Receipt, error) {
+	receipt := &Receipt{}
+	err := c.do(ctx, http.MethodGet, "/receipt", url.Values{"hash": {hex.EncodeToString(hash.Bytes())}}, nil, receipt)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// SendTransaction submits a signed transaction and returns the hash under
+// which it is pending
+func (c *Client) SendTransaction(ctx context.Context, tx *types.Transaction) (ibft.Hash, error) {
+	type txOutput struct {
+		To     string `json:"to"`
+		Amount string `json:"amount"`
+	}
+	req := struct {
+		Type      uint8      `json:"type,omitempty"`
+		From      string     `json:"from"`
+		To        string     `json:"to"`
+		Amount    string     `json:"amount"`
+		Outputs   []txOutput `json:"outputs,omitempty"`
+		Reference string     `json:"reference,omitempty"`
+		Nonce     uint64     `json:"nonce,omitempty"`
+		Signature string     `json:"signature"`
+	}{
+		Type:      tx.Type,
+		From:      hex.EncodeToString(tx.From.Bytes()),
+		To:        hex.EncodeToString(tx.To.Bytes()),
+		Amount:    tx.Amount.String(),
+		Reference: hex.EncodeToString(tx.Reference),
+		Nonce:     tx.Nonce,
+		Signature: hex.EncodeToString(tx.Signature),
+	}
+	for _, o := range tx.Outputs {
+		req.Outputs = append(req.Outputs, txOutput{hex.EncodeToString(o.To.Bytes()), o.Amount.String()})
+	}
+	result := struct {
+		Hash hexHash `json:"hash"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/submit", nil, req, &result); err != nil {
+		return ibft.Hash{}, err
+	}
+	return ibft.Hash(result.Hash), nil
+}
@@ -0,0 +1,280 @@
+package slashclient_test
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/blockchain"
+	"bitbucket.org/ventureslash/go-slash-currency/endpoint"
+	"bitbucket.org/ventureslash/go-slash-currency/slashclient"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func init() {
+	flag.Set("webhooks", "")
+	flag.Set("log-file", "")
+}
+
+// testCurrency serves a blockchain stored in a temporary directory and keeps
+// submitted transactions pending
+type testCurrency struct {
+	ep      *endpoint.Endpoint
+	bc      *blockchain.BlockChain
+	pending []*types.Transaction
+}
+
+func (c *testCurrency) DecodeProposal(*ibft.EncodedProposal) (ibft.Proposal, error) { return nil, nil }
+func (c *testCurrency) BlockChain() *blockchain.BlockChain                          { return c.bc }
+func (c *testCurrency) PendingTransactions() []*types.Transaction                   { return c.pending }
+func (c *testCurrency) GetBalance(addr ibft.Address) *big.Int                       { return c.bc.State().GetBalance(addr) }
+func (c *testCurrency) Status() endpoint.NodeStatus                                 { return endpoint.NodeStatus{} }
+
+func (c *testCurrency) SubmitTransaction(tx *types.Transaction) error {
+	tx = types.NewTransaction(tx.From, tx.To, tx.Amount)
+	c.pending = append(c.pending, tx)
+	c.ep.PublishPendingTransaction(tx)
+	return nil
+}
+
+var (
+	issuerKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+	alice = ibft.Address{1}
+	bob   = ibft.Address{2}
+)
+
+func mustAddress(s string) ibft.Address {
+	bytes, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	addr := ibft.Address{}
+	addr.FromBytes(bytes)
+	return addr
+}
+
+// newTestNode serves an endpoint whose chain holds a block crediting alice
+func newTestNode() (*testCurrency, *httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "slashclient")
+	if err != nil {
+		panic(err)
+	}
+	genesis := blockchain.DefaultGenesis()
+	genesis.Config.Issuance.Issuers = []ibft.Address{mustAddress(hex.EncodeToString(crypto.PubkeyToAddress(issuerKey.PublicKey).Bytes()))}
+	bc, err := blockchain.NewWithGenesis(dir, genesis)
+	if err != nil {
+		panic(err)
+	}
+	mint, err := types.AddMintSignature(types.NewMint(alice, big.NewInt(100)), issuerKey)
+	if err != nil {
+		panic(err)
+	}
+	block := types.NewBlock(&types.Header{
+		Number:     big.NewInt(1),
+		ParentHash: bc.CurrentBlock().Hash(),
+		Time:       big.NewInt(1),
+	}, types.Transactions{mint})
+	if err := bc.InsertChain([]*types.Block{block}); err != nil {
+		panic(err)
+	}
+
+	cur := &testCurrency{ep: endpoint.New(), bc: bc}
+	cur.ep.Currency = cur
+	server := httptest.NewServer(cur.ep)
+	return cur, server, func() {
+		server.Close()
+		cur.ep.Stop(context.Background())
+		os.RemoveAll(dir)
+	}
+}
+
+func TestClientReads(t *testing.T) {
+	cur, server, cleanup := newTestNode()
+	defer cleanup()
+	client := slashclient.New(server.URL)
+	ctx := context.Background()
+
+	balance, err := client.Balance(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Int64() != 100 {
+		t.Fatalf("got balance %v, want 100", balance)
+	}
+	if balance, err = client.BalanceAt(ctx, alice, 0); err != nil || balance.Sign() != 0 {
+		t.Fatalf("got balance %v at genesis (%v), want 0", balance, err)
+	}
+
+	head, err := client.HeadBlock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Number != 1 || head.Hash != cur.bc.CurrentBlock().Hash() || len(head.Transactions) != 1 {
+		t.Fatalf("unexpected head block: %+v", head)
+	}
+	parent, err := client.BlockByHash(ctx, head.ParentHash)
+	if err != nil || parent.Number != 0 {
+		t.Fatalf("unexpected parent block: %+v (%v)", parent, err)
+	}
+	blocks, err := client.Blocks(ctx, 2, 10)
+	if err != nil || len(blocks) != 2 || blocks[0].Number != 1 {
+		t.Fatalf("unexpected blocks: %v (%v)", blocks, err)
+	}
+
+	hash := head.Transactions[0].Hash
+	tx, err := client.Transaction(ctx, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.From != types.MintAccount || tx.To != alice || tx.Amount.Int64() != 100 || *tx.BlockNumber != 1 {
+		t.Fatalf("unexpected transaction: %+v", tx)
+	}
+	receipt, err := client.Receipt(ctx, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful || receipt.BlockHash != head.Hash {
+		t.Fatalf("unexpected receipt: %+v", receipt)
+	}
+	if _, err := client.Receipt(ctx, ibft.Hash{}); err != slashclient.ErrNotFound {
+		t.Fatalf("got %v for an unknown receipt, want ErrNotFound", err)
+	}
+
+	account, err := client.Account(ctx, alice, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance.Int64() != 100 || len(account.Transactions) != 1 || account.Transactions[0].Hash != hash {
+		t.Fatalf("unexpected account: %+v", account)
+	}
+}
+
+func TestClientSubmitAndSubscribe(t *testing.T) {
+	cur, server, cleanup := newTestNode()
+	defer cleanup()
+	client := slashclient.New(server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pending := make(chan *types.Transaction, 1)
+	sub, err := client.SubscribePendingTransactions(ctx, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	heads := make(chan *slashclient.Head, 1)
+	headSub, err := client.SubscribeNewHeads(ctx, heads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer headSub.Unsubscribe()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	sender := ibft.Address{}
+	sender.FromBytes(crypto.PubkeyToAddress(key.PublicKey).Bytes())
+	tx := types.NewTransaction(sender, bob, big.NewInt(10))
+	if _, err := client.SendTransaction(ctx, tx); err == nil {
+		t.Fatal("sent an unsigned transaction")
+	}
+	signed, err := types.SignTx(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := client.SendTransaction(ctx, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != tx.Hash() {
+		t.Fatalf("got hash %x", hash.Bytes())
+	}
+
+	select {
+	case got := <-pending:
+		if got.Hash() != hash {
+			t.Fatalf("got pending transaction %x, want %x", got.Hash().Bytes(), hash.Bytes())
+		}
+	case err := <-sub.Err():
+		t.Fatal(err)
+	case <-ctx.Done():
+		t.Fatal("no pending transaction notification")
+	}
+	if tx, err := client.Transaction(ctx, hash); err != nil || !tx.Pending {
+		t.Fatalf("unexpected pending transaction: %+v (%v)", tx, err)
+	}
+
+	block := cur.bc.CurrentBlock()
+	cur.ep.PublishBlock(block, cur.bc.GetReceiptsByHash(block.Hash()))
+	select {
+	case head := <-heads:
+		if head.Hash != block.Hash() || head.Header.Number.Uint64() != 1 {
+			t.Fatalf("unexpected head: %+v", head)
+		}
+	case err := <-headSub.Err():
+		t.Fatal(err)
+	case <-ctx.Done():
+		t.Fatal("no new head notification")
+	}
+}
+
+func TestPaymentReference(t *testing.T) {
+	cur, server, cleanup := newTestNode()
+	defer cleanup()
+	client := slashclient.New(server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logs := make(chan *slashclient.Log, 2)
+	sub, err := client.SubscribeLogs(ctx, bob, logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	parent := cur.bc.CurrentBlock()
+	block := types.NewBlock(&types.Header{
+		Number:     big.NewInt(2),
+		ParentHash: parent.Hash(),
+		Time:       big.NewInt(2),
+	}, types.Transactions{
+		types.NewPayment(alice, bob, big.NewInt(10), []byte("INV-1")),
+		types.NewPayment(alice, bob, big.NewInt(20), []byte("INV-2")),
+	})
+	if err := cur.bc.InsertChain([]*types.Block{block}); err != nil {
+		panic(err)
+	}
+
+	txs, err := client.PaymentsByReference(ctx, bob, []byte("INV-2"), 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].Hash != block.Transactions[1].Hash() || string(txs[0].Reference) != "INV-2" {
+		t.Fatalf("unexpected payments: %+v", txs)
+	}
+	if txs, err := client.PaymentsByReference(ctx, alice, []byte("INV-3"), 0, 10); err != nil || len(txs) != 0 {
+		t.Fatalf("got payments %+v (%v) for an unknown reference", txs, err)
+	}
+
+	cur.ep.PublishBlock(block, cur.bc.GetReceiptsByHash(block.Hash()))
+	select {
+	case log := <-logs:
+		if log.Reference != hex.EncodeToString([]byte("INV-1")) || log.Amount.Int64() != 10 {
+			t.Fatalf("unexpected log: %+v", log)
+		}
+	case err := <-sub.Err():
+		t.Fatal(err)
+	case <-ctx.Done():
+		t.Fatal("no log notification")
+	}
+}
@@ -0,0 +1,218 @@
+package slashclient
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/gorilla/websocket"
+)
+
+// Subscription delivers the notifications of a topic until it is closed or
+// the connection fails
+type Subscription struct {
+	conn      *websocket.Conn
+	err       chan error
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+// message is a websocket message of the endpoint
+type message struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Err returns a channel receiving the error ending the subscription. It is
+// closed by Unsubscribe.
+func (s *Subscription) Err() <-chan error {
+	return s.err
+}
+
+// Unsubscribe closes the subscription
+func (s *Subscription) Unsubscribe() {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		s.conn.Close()
+	})
+}
+
+// SubscribeNewHeads delivers every new block header to ch
+func (c *Client) SubscribeNewHeads(ctx context.Context, ch chan<- *Head) (*Subscription, error) {
+	return c.subscribe(ctx, "newHeads", nil, func(data json.RawMessage, quit chan struct{}) error {
+		head := &Head{}
+		if err := json.Unmarshal(data, head); err != nil {
+			return err
+		}
+		select {
+		case ch <- head:
+		case <-quit:
+		}
+		return nil
+	})
+}
+
+// SubscribePendingTransactions delivers every transaction added to the pending
+// pool to ch
+func (c *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (*Subscription, error) {
+	return c.subscribe(ctx, "newPendingTransactions", nil, func(data json.RawMessage, quit chan struct{}) error {
+		tx := &types.Transaction{}
+		if err := json.Unmarshal(data, tx); err != nil {
+			return err
+		}
+		select {
+		case ch <- tx:
+		case <-quit:
+		}
+		return nil
+	})
+}
+
+// SubscribeLogs delivers the committed transfers sent or received by addr to
+// ch
+func (c *Client) SubscribeLogs(ctx context.Context, addr ibft.Address, ch chan<- *Log) (*Subscription, error) {
+	return c.subscribe(ctx, "logs", &addr, func(data json.RawMessage, quit chan struct{}) error {
+		log := &Log{}
+		if err := json.Unmarshal(data, log); err != nil {
+			return err
+		}
+		select {
+		case ch <- log:
+		case <-quit:
+		}
+		return nil
+	})
+}
+
+// SubscribeSyncing delivers the start and end of the synchronizations of the
+// node to ch
+func (c *Client) SubscribeSyncing(ctx context.Context, ch chan<- *SyncStatus) (*Subscription, error) {
+	return c.subscribe(ctx, "syncing", nil, func(data json.RawMessage, quit chan struct{}) error {
+		status := &SyncStatus{}
+		if err := json.Unmarshal(data, status); err != nil {
+			return err
+		}
+		select {
+		case ch <- status:
+		case <-quit:
+		}
+		return nil
+	})
+}
+
+// subscribe opens a websocket subscribed to topic and passes the result of
+// each notification to deliver
+func (c *Client) subscribe(ctx context.Context, topic string, addr *ibft.Address, deliver func(json.RawMessage, chan struct{}) error) (*Subscription, error) {
+	dialer := websocket.Dialer{}
+	if t, ok := c.http.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = t.TLSClientConfig
+	}
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	wsURL := "ws" + strings.TrimPrefix(c.url, "http") + "/ws"
+	conn, _, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	req := struct {
+		Type string            `json:"type"`
+		Data map[string]string `json:"data"`
+	}{"subscribe", map[string]string{"topic": topic}}
+	if addr != nil {
+		req.Data["address"] = hex.EncodeToString(addr.Bytes())
+	}
+	if err := conn.WriteJSON(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Wait for the subscription to be acknowledged, skipping the connection
+	// message and the consensus events broadcast to every client
+	var id string
+	for id == "" {
+		msg := message{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		switch msg.Type {
+		case "error":
+			conn.Close()
+			return nil, errors.New(unquote(msg.Data))
+		case "subscribed":
+			sub := struct {
+				ID string `json:"id"`
+			}{}
+			if err := json.Unmarshal(msg.Data, &sub); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			id = sub.ID
+		}
+	}
+
+	sub := &Subscription{
+		conn: conn,
+		err:  make(chan error, 1),
+		quit: make(chan struct{}),
+	}
+	go sub.run(id, deliver)
+	return sub, nil
+}
+
+func (s *Subscription) run(id string, deliver func(json.RawMessage, chan struct{}) error) {
+	defer close(s.err)
+	for {
+		msg := message{}
+		err := s.conn.ReadJSON(&msg)
+		if err == nil {
+			err = s.handle(id, msg, deliver)
+		}
+		if err != nil {
+			select {
+			case <-s.quit:
+			default:
+				s.err <- err
+				s.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+func (s *Subscription) handle(id string, msg message, deliver func(json.RawMessage, chan struct{}) error) error {
+	switch msg.Type {
+	case "error":
+		return errors.New(unquote(msg.Data))
+	case "subscription":
+		result := struct {
+			Subscription string          `json:"subscription"`
+			Result       json.RawMessage `json:"result"`
+		}{}
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			return err
+		}
+		if result.Subscription == id {
+			return deliver(result.Result, s.quit)
+		}
+	}
+	return nil
+}
+
+// unquote returns the string held by a JSON value, or the raw value
+func unquote(data json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return string(data)
+	}
+	return s
+}
@@ -0,0 +1,249 @@
+package slashclient
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+// Block is a block served by the endpoint
+type Block struct {
+	Number     uint64
+	Hash       ibft.Hash
+	ParentHash ibft.Hash
+	Time       uint64
+	TxCount    int
+	// Only set by the single block queries
+	Transactions []*Transaction
+}
+
+// Transaction is a committed or pending transaction. Block fields and status
+// are only set once the transaction is committed, Outputs only for batch
+// transfers, Reference only for payments and Nonce only for mints.
+type Transaction struct {
+	Hash        ibft.Hash
+	Type        uint8
+	From        ibft.Address
+	To          ibft.Address
+	Amount      *big.Int
+	Outputs     []*types.TxOutput
+	Reference   []byte
+	Nonce       uint64
+	BlockNumber *uint64
+	BlockHash   *ibft.Hash
+	Status      *uint64
+	Pending     bool
+}
+
+// Receipt is the result of a committed transaction
+type Receipt struct {
+	TxHash      ibft.Hash
+	Status      uint64
+	BlockHash   ibft.Hash
+	BlockNumber uint64
+	Index       uint64
+}
+
+// Account is the balance of an account with its latest transactions
+type Account struct {
+	Address      ibft.Address
+	Balance      *big.Int
+	Transactions []*Transaction
+}
+
+// Head is a new block notified to newHeads subscribers
+type Head struct {
+	Hash   ibft.Hash     `json:"hash"`
+	Header *types.Header `json:"header"`
+}
+
+// Log is a transfer notified to the logs subscribers of an address. The
+// reference of a payment is hex encoded.
+type Log struct {
+	BlockNumber *big.Int     `json:"blockNumber"`
+	BlockHash   ibft.Hash    `json:"blockHash"`
+	TxHash      ibft.Hash    `json:"txHash"`
+	From        ibft.Address `json:"from"`
+	To          ibft.Address `json:"to"`
+	Amount      *big.Int     `json:"amount"`
+	Reference   string       `json:"reference,omitempty"`
+	Status      uint64       `json:"status"`
+}
+
+// SyncStatus is notified to syncing subscribers when a synchronization starts
+// or ends
+type SyncStatus struct {
+	Syncing      bool   `json:"syncing"`
+	CurrentBlock uint64 `json:"currentBlock"`
+}
+
+// hexHash, hexAddress, hexBytes and decimal decode the hex and decimal strings
+// used by the JSON routes of the endpoint
+type (
+	hexHash    ibft.Hash
+	hexAddress ibft.Address
+	hexBytes   []byte
+	decimal    big.Int
+)
+
+func decodeHex(text []byte, length int) ([]byte, error) {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != length {
+		return nil, fmt.Errorf("got %d bytes, want %d", len(b), length)
+	}
+	return b, nil
+}
+
+func (h *hexHash) UnmarshalText(text []byte) error {
+	b, err := decodeHex(text, len(h))
+	if err != nil {
+		return fmt.Errorf("invalid hash %q: %v", text, err)
+	}
+	*h = hexHash(ibft.BytesToHash(b))
+	return nil
+}
+
+func (a *hexAddress) UnmarshalText(text []byte) error {
+	b, err := decodeHex(text, len(a))
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", text, err)
+	}
+	addr := ibft.Address{}
+	addr.FromBytes(b)
+	*a = hexAddress(addr)
+	return nil
+}
+
+func (b *hexBytes) UnmarshalText(text []byte) error {
+	data, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid hex %q: %v", text, err)
+	}
+	*b = data
+	return nil
+}
+
+func (d *decimal) UnmarshalText(text []byte) error {
+	if _, ok := (*big.Int)(d).SetString(string(text), 10); !ok {
+		return fmt.Errorf("invalid amount %q", text)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Block) UnmarshalJSON(data []byte) error {
+	v := struct {
+		Number       uint64         `json:"number"`
+		Hash         hexHash        `json:"hash"`
+		ParentHash   hexHash        `json:"parentHash"`
+		Time         uint64         `json:"timestamp"`
+		TxCount      int            `json:"txCount"`
+		Transactions []*Transaction `json:"transactions"`
+	}{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*b = Block{
+		Number:       v.Number,
+		Hash:         ibft.Hash(v.Hash),
+		ParentHash:   ibft.Hash(v.ParentHash),
+		Time:         v.Time,
+		TxCount:      v.TxCount,
+		Transactions: v.Transactions,
+	}
+	return nil
+}
+
+// output is a payment of a batch transfer
+type output struct {
+	To     hexAddress `json:"to"`
+	Amount decimal    `json:"amount"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	v := struct {
+		Hash        hexHash    `json:"hash"`
+		Type        uint8      `json:"type"`
+		From        hexAddress `json:"from"`
+		To          hexAddress `json:"to"`
+		Amount      decimal    `json:"amount"`
+		Outputs     []output   `json:"outputs"`
+		Reference   hexBytes   `json:"reference"`
+		Nonce       uint64     `json:"nonce"`
+		BlockNumber *uint64    `json:"blockNumber"`
+		BlockHash   *hexHash   `json:"blockHash"`
+		Status      *uint64    `json:"status"`
+		Pending     bool       `json:"pending"`
+	}{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*tx = Transaction{
+		Hash:        ibft.Hash(v.Hash),
+		Type:        v.Type,
+		From:        ibft.Address(v.From),
+		To:          ibft.Address(v.To),
+		Amount:      (*big.Int)(&v.Amount),
+		Reference:   []byte(v.Reference),
+		Nonce:       v.Nonce,
+		BlockNumber: v.BlockNumber,
+		Status:      v.Status,
+		Pending:     v.Pending,
+	}
+	for _, o := range v.Outputs {
+		tx.Outputs = append(tx.Outputs, &types.TxOutput{To: ibft.Address(o.To), Amount: new(big.Int).Set((*big.Int)(&o.Amount))})
+	}
+	if v.BlockHash != nil {
+		hash := ibft.Hash(*v.BlockHash)
+		tx.BlockHash = &hash
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *Receipt) UnmarshalJSON(data []byte) error {
+	v := struct {
+		TxHash      hexHash `json:"transactionHash"`
+		Status      uint64  `json:"status"`
+		BlockHash   hexHash `json:"blockHash"`
+		BlockNumber uint64  `json:"blockNumber"`
+		Index       uint64  `json:"index"`
+	}{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Receipt{
+		TxHash:      ibft.Hash(v.TxHash),
+		Status:      v.Status,
+		BlockHash:   ibft.Hash(v.BlockHash),
+		BlockNumber: v.BlockNumber,
+		Index:       v.Index,
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *Account) UnmarshalJSON(data []byte) error {
+	v := struct {
+		Address      hexAddress     `json:"address"`
+		Balance      decimal        `json:"balance"`
+		Transactions []*Transaction `json:"transactions"`
+	}{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = Account{
+		Address:      ibft.Address(v.Address),
+		Balance:      (*big.Int)(&v.Balance),
+		Transactions: v.Transactions,
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+)
+
+// DemurragePolicy charges the balances of the accounts at the end of a block
+// and returns what each account was charged and received
+type DemurragePolicy interface {
+	Apply(s *StateDB, number uint64) types.DemurrageCharges
+}
+
+// NewDemurragePolicy returns the policy configured by the rules of a network
+func NewDemurragePolicy(config *types.ChainConfig) DemurragePolicy {
+	d := config.Demurrage
+	exempt := map[ibft.Address]bool{}
+	for _, addr := range d.Exempt {
+		exempt[addr] = true
+	}
+	charge := &demurrage{interval: d.Interval, rate: new(big.Int).SetUint64(d.Rate), exempt: exempt}
+	switch d.Destination {
+	case types.DemurrageTreasury:
+		exempt[d.Treasury] = true
+		return &treasuryPolicy{demurrage: charge, treasury: d.Treasury}
+	case types.DemurrageRedistribute:
+		return &redistributePolicy{demurrage: charge}
+	default:
+		return charge
+	}
+}
+
+// demurrage burns the amount it charges
+type demurrage struct {
+	interval uint64
+	rate     *big.Int
+	exempt   map[ibft.Address]bool
+}
+
+// charge subtracts the demurrage from the balances of the accounts not exempt
+// when number is a demurrage block. The total charged is returned.
+func (d *demurrage) charge(s *StateDB, number uint64) (types.DemurrageCharges, *big.Int) {
+	charges := types.DemurrageCharges{}
+	total := new(big.Int)
+	if d.interval == 0 || number == 0 || number%d.interval != 0 {
+		return charges, total
+	}
+	for addr, o := range s.GetStateObjects() {
+		if d.exempt[addr] {
+			continue
+		}
+		amount := new(big.Int).Div(o.GetBalance(), d.rate)
+		if amount.Sign() == 0 {
+			continue
+		}
+		s.subBalance(addr, amount)
+		total.Add(total, amount)
+		charges = append(charges, &types.DemurrageCharge{
+			Block:   number,
+			Address: addr,
+			Amount:  amount,
+			Share:   new(big.Int),
+		})
+	}
+	sort.Slice(charges, func(i, j int) bool {
+		return bytes.Compare(charges[i].Address.Bytes(), charges[j].Address.Bytes()) < 0
+	})
+	return charges, total
+}
+
+func (d *demurrage) Apply(s *StateDB, number uint64) types.DemurrageCharges {
+	charges, total := d.charge(s, number)
+	s.burnDemurrage(total)
+	return charges
+}
+
+// treasuryPolicy credits the amount it charges to the treasury account
+type treasuryPolicy struct {
+	*demurrage
+	treasury ibft.Address
+}
+
+func (p *treasuryPolicy) Apply(s *StateDB, number uint64) types.DemurrageCharges {
+	charges, total := p.charge(s, number)
+	if total.Sign() == 0 {
+		return charges
+	}
+	s.addBalance(p.treasury, total)
+	charges = append(charges, &types.DemurrageCharge{
+		Block:   number,
+		Address: p.treasury,
+		Amount:  new(big.Int),
+		Share:   total,
+	})
+	sort.Slice(charges, func(i, j int) bool {
+		return bytes.Compare(charges[i].Address.Bytes(), charges[j].Address.Bytes()) < 0
+	})
+	return charges
+}
+
+// redistributePolicy shares the amount it charges equally between the
+// charged accounts
+type redistributePolicy struct {
+	*demurrage
+}
+
+func (p *redistributePolicy) Apply(s *StateDB, number uint64) types.DemurrageCharges {
+	charges, total := p.charge(s, number)
+	if len(charges) == 0 {
+		return charges
+	}
+	share, remainder := new(big.Int).DivMod(total, big.NewInt(int64(len(charges))), new(big.Int))
+	for _, charge := range charges {
+		s.addBalance(charge.Address, share)
+		charge.Share = new(big.Int).Set(share)
+	}
+	s.burnDemurrage(remainder)
+	return charges
+}
@@ -1,72 +1,336 @@
 package state
 
 import (
-	"encoding/hex"
+	"bytes"
+	"errors"
 	"log"
 	"math/big"
+	"sort"
 
 	"bitbucket.org/ventureslash/go-ibft"
 	"bitbucket.org/ventureslash/go-slash-currency/types"
 )
 
+var errInsufficientBalance = errors.New("insufficient balance")
+
 type StateDB struct {
+	config       *types.ChainConfig
+	demurrage    DemurragePolicy
 	stateObjects map[ibft.Address]StateObject
+	// Accounts modified since the last call to CommitDiff
+	dirties map[ibft.Address]struct{}
+	// Demurrage charged since the last call to CommitCharges
+	charges types.DemurrageCharges
+	stats   *types.SupplyStats
+	// IDs of the committed mints, and of those committed since the last call
+	// to CommitMints
+	mints    map[ibft.Hash]struct{}
+	newMints []ibft.Hash
 }
 
-func New() *StateDB {
+// New returns an empty state following the rules of config
+func New(config *types.ChainConfig) *StateDB {
 	return &StateDB{
+		config:       config,
+		demurrage:    NewDemurragePolicy(config),
 		stateObjects: make(map[ibft.Address]StateObject),
+		dirties:      make(map[ibft.Address]struct{}),
+		mints:        make(map[ibft.Hash]struct{}),
+		stats: &types.SupplyStats{
+			Supply:          new(big.Int),
+			Minted:          new(big.Int),
+			Burned:          new(big.Int),
+			DemurrageBurned: new(big.Int),
+		},
 	}
 }
 
 // ProcessBlock returns receitps of a block and update state
 func (s *StateDB) ProcessBlock(b *types.Block) ([]*types.Receipt, error) {
-
-	key := "0dedc69acd6405e2459c93efb0dbfa8d9c0b5ccb"
-	bytes, _ := hex.DecodeString(key)
-	rootAccount := ibft.Address{}
-	rootAccount.FromBytes(bytes)
-
 	receipts := []*types.Receipt{}
+	n := b.Number().Uint64()
 	for _, t := range b.Transactions {
 		log.Print("Processing transaction ", t.From)
 
-		res := uint64(1)
-		sender := s.GetStateObject(t.From)
-		receiver := s.GetStateObject(t.To)
-		amount := t.Amount
-		if t.From == rootAccount {
-			log.Print("Transaction from root account")
-			receiver.AddBalance(amount)
-			receipts = append(receipts, types.NewReceipt(t.Hash(), res))
-			continue
+		receipt := types.NewReceipt(t.Hash(), types.ReceiptStatusSuccessful)
+		var err error
+		switch t.Type {
+		case types.LegacyTxType:
+			if s.config.Issuance.IsLegacyMint(t, n) {
+				s.legacyMint(t, receipt)
+				break
+			}
+			err = s.transfer(t)
+		case types.MintTxType:
+			err = s.mint(t, n, receipt)
+		case types.BurnTxType:
+			err = s.burn(t, receipt)
+		case types.BatchTxType:
+			err = s.batch(t, receipt)
+		case types.PaymentTxType:
+			err = types.ErrReferenceTooLong
+			if len(t.Reference) <= types.MaxReferenceLength {
+				err = s.transfer(t)
+			}
+		default:
+			err = types.ErrTxTypeNotSupported
+		}
+		if err != nil {
+			log.Print("Transaction failed: ", err)
+			receipt.Status = types.ReceiptStatusFailed
 		}
+		receipts = append(receipts, receipt)
+	}
+	s.charges = append(s.charges, s.demurrage.Apply(s, n)...)
+	return receipts, nil
+}
+
+// transfer moves the amount of t, which must be positive, from its sender to
+// its recipient
+func (s *StateDB) transfer(t *types.Transaction) error {
+	if err := t.CheckAmounts(); err != nil {
+		return err
+	}
+	if !s.subBalance(t.From, t.Amount) {
+		return errInsufficientBalance
+	}
+	s.addBalance(t.To, t.Amount)
+	return nil
+}
 
-		if !sender.SubBalance(amount) {
-			res = 0
-		} else {
-			receiver.AddBalance(amount)
+// mint credits the recipient of a mint approved by the issuers, within the
+// supply cap. The allocations of the genesis block need no approval. A mint
+// can only be committed once, whatever its signatures.
+func (s *StateDB) mint(t *types.Transaction, number uint64, receipt *types.Receipt) error {
+	issuance := &s.config.Issuance
+	id := t.MintID()
+	if s.IsMinted(id) {
+		return types.ErrMintReplayed
+	}
+	if number != 0 {
+		if err := issuance.Approve(t); err != nil {
+			return err
 		}
-		receipts = append(receipts, types.NewReceipt(t.Hash(), res))
 	}
-	n := b.Number().Uint64()
-	if n != 0 && n%4320 == 0 { // 4320 = one day
-		s.applyDemurrage()
+	supply := new(big.Int).Add(s.stats.Supply, t.Amount)
+	if cap := issuance.CapAt(number); cap != nil && supply.Cmp(cap) > 0 {
+		return types.ErrSupplyCap
 	}
-	return receipts, nil
+	s.stats.Supply = supply
+	s.stats.Minted.Add(s.stats.Minted, t.Amount)
+	s.mints[id] = struct{}{}
+	s.newMints = append(s.newMints, id)
+	s.addBalance(t.To, t.Amount)
+	receipt.Logs = []*types.ReceiptLog{{Kind: types.LogMint, Account: t.To, Amount: t.Amount}}
+	return nil
+}
+
+// legacyMint credits the recipient of a transfer from the legacy root account,
+// which needs neither approval nor room under the supply cap
+func (s *StateDB) legacyMint(t *types.Transaction, receipt *types.Receipt) {
+	s.stats.Supply.Add(s.stats.Supply, t.Amount)
+	s.stats.Minted.Add(s.stats.Minted, t.Amount)
+	s.addBalance(t.To, t.Amount)
+	receipt.Logs = []*types.ReceiptLog{{Kind: types.LogMint, Account: t.To, Amount: t.Amount}}
+}
+
+// burn destroys the amount of t taken from its sender
+func (s *StateDB) burn(t *types.Transaction, receipt *types.Receipt) error {
+	if !s.subBalance(t.From, t.Amount) {
+		return errInsufficientBalance
+	}
+	s.stats.Supply.Sub(s.stats.Supply, t.Amount)
+	s.stats.Burned.Add(s.stats.Burned, t.Amount)
+	receipt.Logs = []*types.ReceiptLog{{Kind: types.LogBurn, Account: t.From, Amount: t.Amount}}
+	return nil
+}
+
+// batch pays all the outputs of t from its sender, or none of them if the
+// balance does not cover their total or one of them is not positive
+func (s *StateDB) batch(t *types.Transaction, receipt *types.Receipt) error {
+	if len(t.Outputs) == 0 || len(t.Outputs) > types.MaxBatchOutputs {
+		return types.ErrBatchSize
+	}
+	if err := t.CheckAmounts(); err != nil {
+		return err
+	}
+	total := new(big.Int)
+	for _, output := range t.Outputs {
+		total.Add(total, output.Amount)
+	}
+	if !s.subBalance(t.From, total) {
+		return errInsufficientBalance
+	}
+	logs := make([]*types.ReceiptLog, 0, len(t.Outputs))
+	for _, output := range t.Outputs {
+		s.addBalance(output.To, output.Amount)
+		logs = append(logs, &types.ReceiptLog{Kind: types.LogOutput, Account: output.To, Amount: output.Amount})
+	}
+	receipt.Logs = logs
+	return nil
+}
+
+// burnDemurrage removes collected demurrage from the total supply
+func (s *StateDB) burnDemurrage(amount *big.Int) {
+	s.stats.Supply.Sub(s.stats.Supply, amount)
+	s.stats.DemurrageBurned.Add(s.stats.DemurrageBurned, amount)
+}
+
+// addBalance credits an account, counting the accounts holding money
+func (s *StateDB) addBalance(addr ibft.Address, amount *big.Int) {
+	o := s.GetStateObject(addr)
+	if o.GetBalance().Sign() == 0 && amount.Sign() > 0 {
+		s.stats.Accounts++
+	}
+	o.AddBalance(amount)
+	s.markDirty(addr)
+}
+
+// subBalance debits an account if its balance is sufficient
+func (s *StateDB) subBalance(addr ibft.Address, amount *big.Int) bool {
+	o := s.GetStateObject(addr)
+	if !o.SubBalance(amount) {
+		return false
+	}
+	if o.GetBalance().Sign() == 0 && amount.Sign() > 0 {
+		s.stats.Accounts--
+	}
+	s.markDirty(addr)
+	return true
+}
+
+// TotalSupply returns the amount of money held by the accounts
+func (s *StateDB) TotalSupply() *big.Int {
+	return new(big.Int).Set(s.stats.Supply)
+}
+
+// Stats returns the accounting of the money supply
+func (s *StateDB) Stats() *types.SupplyStats {
+	return s.stats.Copy()
+}
+
+// SetStats restores the totals of a state rebuilt from diffs. The supply and
+// the number of accounts follow the balances and are kept.
+func (s *StateDB) SetStats(stats *types.SupplyStats) {
+	s.stats.Minted = new(big.Int).Set(stats.Minted)
+	s.stats.Burned = new(big.Int).Set(stats.Burned)
+	s.stats.DemurrageBurned = new(big.Int).Set(stats.DemurrageBurned)
+}
+
+// TopHolders returns the count accounts holding the most money, richest
+// first
+func (s *StateDB) TopHolders(count int) types.StateDiff {
+	holders := make(types.StateDiff, 0, s.stats.Accounts)
+	for addr, o := range s.stateObjects {
+		if balance := o.GetBalance(); balance.Sign() > 0 {
+			holders = append(holders, &types.AccountState{Address: addr, Balance: balance})
+		}
+	}
+	sort.Slice(holders, func(i, j int) bool {
+		if c := holders[i].Balance.Cmp(holders[j].Balance); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(holders[i].Address.Bytes(), holders[j].Address.Bytes()) < 0
+	})
+	if len(holders) > count {
+		holders = holders[:count]
+	}
+	return holders
+}
+
+func (s *StateDB) markDirty(addr ibft.Address) {
+	s.dirties[addr] = struct{}{}
+}
+
+// CommitDiff returns the accounts modified since the last call along with
+// their current balance, sorted by address.
+func (s *StateDB) CommitDiff() types.StateDiff {
+	diff := make(types.StateDiff, 0, len(s.dirties))
+	for addr := range s.dirties {
+		diff = append(diff, &types.AccountState{
+			Address: addr,
+			Balance: s.GetBalance(addr),
+		})
+	}
+	s.dirties = make(map[ibft.Address]struct{})
+	sortAccounts(diff)
+	return diff
+}
+
+// IsMinted reports whether the mint identified by id is committed
+func (s *StateDB) IsMinted(id ibft.Hash) bool {
+	_, ok := s.mints[id]
+	return ok
+}
+
+// CommitMints returns the IDs of the mints committed since the last call
+func (s *StateDB) CommitMints() []ibft.Hash {
+	mints := s.newMints
+	s.newMints = nil
+	return mints
+}
+
+// ApplyMints marks the mints identified by ids as committed
+func (s *StateDB) ApplyMints(ids []ibft.Hash) {
+	for _, id := range ids {
+		s.mints[id] = struct{}{}
+	}
+}
+
+// Mints returns the IDs of every committed mint, sorted
+func (s *StateDB) Mints() []ibft.Hash {
+	ids := make([]ibft.Hash, 0, len(s.mints))
+	for id := range s.mints {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i].Bytes(), ids[j].Bytes()) < 0
+	})
+	return ids
 }
 
-func (s *StateDB) applyDemurrage() {
-	rootAccount := ibft.Address{0}
-	for key, o := range s.GetStateObjects() {
-		if key == rootAccount {
-			continue
+// CommitCharges returns the demurrage charged since the last call
+func (s *StateDB) CommitCharges() types.DemurrageCharges {
+	charges := s.charges
+	s.charges = nil
+	return charges
+}
+
+// ApplyDiff overwrites the balances of the accounts listed in diff
+func (s *StateDB) ApplyDiff(diff types.StateDiff) {
+	for _, account := range diff {
+		o := s.GetStateObject(account.Address)
+		before := o.GetBalance()
+		s.stats.Supply.Add(s.stats.Supply, new(big.Int).Sub(account.Balance, before))
+		switch {
+		case before.Sign() == 0 && account.Balance.Sign() > 0:
+			s.stats.Accounts++
+		case before.Sign() > 0 && account.Balance.Sign() == 0:
+			s.stats.Accounts--
 		}
-		dem := new(big.Int).Div(o.GetBalance(), big.NewInt(3000))
-		o.SubBalance(dem)
+		o.SetBalance(account.Balance)
 	}
 }
 
+// Dump returns the balance of every known account, sorted by address.
+func (s *StateDB) Dump() types.StateDiff {
+	accounts := make(types.StateDiff, 0, len(s.stateObjects))
+	for addr, o := range s.stateObjects {
+		accounts = append(accounts, &types.AccountState{
+			Address: addr,
+			Balance: o.GetBalance(),
+		})
+	}
+	sortAccounts(accounts)
+	return accounts
+}
+
+func sortAccounts(accounts types.StateDiff) {
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i].Address.Bytes(), accounts[j].Address.Bytes()) < 0
+	})
+}
+
 // GetStateObject returns the state object associated to an address
 func (s *StateDB) GetStateObject(addr ibft.Address) StateObject {
 	state := s.stateObjects[addr]
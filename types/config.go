@@ -0,0 +1,54 @@
+package types
+
+import (
+	"math/big"
+
+	"bitbucket.org/ventureslash/go-ibft"
+)
+
+// Destinations of the demurrage collected from the balances
+const (
+	// DemurrageBurn removes the collected amount from the supply
+	DemurrageBurn = "burn"
+	// DemurrageTreasury credits the collected amount to the treasury account
+	DemurrageTreasury = "treasury"
+	// DemurrageRedistribute shares the collected amount equally between the
+	// charged accounts. The remainder of the division is burned.
+	DemurrageRedistribute = "redistribute"
+)
+
+// ChainConfig holds the rules of a network, set by its genesis
+type ChainConfig struct {
+	// ChainID separates the transaction signatures of networks
+	ChainID  *big.Int
+	Issuance IssuanceConfig
+	// BlockInterval is the number of seconds between two blocks
+	BlockInterval uint64
+	Demurrage     DemurrageConfig
+}
+
+// DemurrageConfig is the monetary policy charging the balances of the accounts
+type DemurrageConfig struct {
+	// Every Interval blocks, each balance loses 1/Rate of its value. A zero
+	// interval disables demurrage.
+	Interval uint64
+	Rate     uint64
+	// Exempt lists the accounts never charged, in addition to the treasury
+	Exempt []ibft.Address
+	// Destination is where the collected amount goes, one of DemurrageBurn,
+	// DemurrageTreasury and DemurrageRedistribute
+	Destination string
+	Treasury    ibft.Address
+}
+
+// DemurrageCharge records the demurrage charged to an account by a block, and
+// the share of the collected amount it received
+type DemurrageCharge struct {
+	Block   uint64       `json:"block"`
+	Address ibft.Address `json:"address"`
+	Amount  *big.Int     `json:"amount"`
+	Share   *big.Int     `json:"share"`
+}
+
+// DemurrageCharges lists the charges of a block, sorted by address
+type DemurrageCharges []*DemurrageCharge
@@ -0,0 +1,190 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MintAccount is the sender of mint transactions and the recipient of burn
+// transactions. It never holds money.
+var MintAccount = ibft.Address{}
+
+var (
+	// ErrMintNotApproved is returned when a mint is not signed by enough
+	// issuers
+	ErrMintNotApproved = errors.New("mint not approved by enough issuers")
+	// ErrSupplyCap is returned when a mint would exceed the supply cap
+	ErrSupplyCap = errors.New("mint exceeds the supply cap")
+	// ErrMintReplayed is returned for a mint whose ID was already minted
+	ErrMintReplayed = errors.New("mint already committed")
+	// ErrUnknownIssuer is returned when a mint is signed by an account that
+	// is not an issuer
+	ErrUnknownIssuer = errors.New("mint signed by an unknown issuer")
+	// ErrDuplicateApproval is returned when a mint carries several
+	// signatures of the same issuer
+	ErrDuplicateApproval = errors.New("mint signed twice by the same issuer")
+)
+
+// IssuanceConfig is the governance of the money supply
+type IssuanceConfig struct {
+	// Issuers approve the mint transactions by signing them, and Threshold
+	// signatures of distinct issuers are needed for a mint to succeed
+	Issuers   []ibft.Address
+	Threshold uint64
+	// Schedule caps the total supply from given blocks, sorted by block. The
+	// supply is not capped before the first entry.
+	Schedule []*SupplyCap
+	// Before LegacyBlock, transfers from LegacyRoot credit their recipient
+	// without debiting the root account, as money was issued before mints
+	// existed. A zero LegacyBlock disables the rule.
+	LegacyRoot  ibft.Address
+	LegacyBlock uint64
+}
+
+// SupplyCap is the maximum total supply from a given block
+type SupplyCap struct {
+	Block uint64
+	Cap   *big.Int
+}
+
+// NewMint returns an unsigned mint of amount to the account to. Its nonce
+// must be set before the issuers sign it when another mint of the same amount
+// to the same account was committed.
+func NewMint(to ibft.Address, amount *big.Int) *Transaction {
+	return &Transaction{Type: MintTxType, From: MintAccount, To: to, Amount: amount}
+}
+
+// MintID identifies the mint tx whatever its signatures, which do not change
+// the money created: it is the signing hash of the mint
+func (s *Transaction) MintID() ibft.Hash {
+	return s.SigningHash()
+}
+
+// NewBurn returns an unsigned burn of amount from the account from
+func NewBurn(from ibft.Address, amount *big.Int) *Transaction {
+	return &Transaction{Type: BurnTxType, From: from, To: MintAccount, Amount: amount}
+}
+
+// IsMint reports whether tx creates money
+func (s *Transaction) IsMint() bool {
+	return s.Type == MintTxType
+}
+
+// IsBurn reports whether tx destroys money
+func (s *Transaction) IsBurn() bool {
+	return s.Type == BurnTxType
+}
+
+// AddMintSignature returns a copy of the mint tx with the signature of an
+// issuer appended, so that issuers can approve a mint one after the other
+func AddMintSignature(tx *Transaction, key *ecdsa.PrivateKey) (*Transaction, error) {
+	if !tx.IsMint() {
+		return nil, errors.New("not a mint transaction")
+	}
+	sig, err := crypto.Sign(tx.SigningHash().Bytes(), key)
+	if err != nil {
+		return nil, err
+	}
+	signed := tx.Copy()
+	signed.Signature = append(signed.Signature, sig...)
+	return signed, nil
+}
+
+// MintSigners returns the addresses that signed the mint tx. The signature of
+// a mint is the concatenation of 65 bytes signatures.
+func MintSigners(tx *Transaction) ([]ibft.Address, error) {
+	if len(tx.Signature) == 0 || len(tx.Signature)%65 != 0 {
+		return nil, ErrInvalidSig
+	}
+	hash := tx.SigningHash().Bytes()
+	signers := make([]ibft.Address, 0, len(tx.Signature)/65)
+	for i := 0; i < len(tx.Signature); i += 65 {
+		pub, err := crypto.SigToPub(hash, tx.Signature[i:i+65])
+		if err != nil {
+			return nil, ErrInvalidSig
+		}
+		addr := ibft.Address{}
+		addr.FromBytes(crypto.PubkeyToAddress(*pub).Bytes())
+		signers = append(signers, addr)
+	}
+	return signers, nil
+}
+
+// Approve returns nil if the mint tx is signed by Threshold issuers. Every
+// signature must be made by a distinct issuer.
+func (c *IssuanceConfig) Approve(tx *Transaction) error {
+	signers, err := MintSigners(tx)
+	if err != nil {
+		return err
+	}
+	approvals := map[ibft.Address]bool{}
+	for _, signer := range signers {
+		if !c.isIssuer(signer) {
+			return ErrUnknownIssuer
+		}
+		if approvals[signer] {
+			return ErrDuplicateApproval
+		}
+		approvals[signer] = true
+	}
+	if c.Threshold == 0 || uint64(len(approvals)) < c.Threshold {
+		return ErrMintNotApproved
+	}
+	return nil
+}
+
+func (c *IssuanceConfig) isIssuer(addr ibft.Address) bool {
+	for _, issuer := range c.Issuers {
+		if addr == issuer {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLegacyMint reports whether the transfer tx of block number creates money
+// under the issuance rule of the chains created before mints
+func (c *IssuanceConfig) IsLegacyMint(tx *Transaction, number uint64) bool {
+	return tx.Type == LegacyTxType && number < c.LegacyBlock && tx.From == c.LegacyRoot
+}
+
+// CapAt returns the supply cap at block number, or nil if the supply is not
+// capped
+func (c *IssuanceConfig) CapAt(number uint64) *big.Int {
+	var cap *big.Int
+	for _, entry := range c.Schedule {
+		if entry.Block > number {
+			break
+		}
+		cap = entry.Cap
+	}
+	return cap
+}
+
+// SupplyStats is the accounting of the money supply at a given block. Supply
+// always equals Minted - Burned - DemurrageBurned, and the sum of the
+// balances.
+type SupplyStats struct {
+	Supply *big.Int
+	Minted *big.Int
+	// Burned is the amount destroyed by burn transactions
+	Burned          *big.Int
+	DemurrageBurned *big.Int
+	// Accounts is the number of accounts holding money
+	Accounts uint64
+}
+
+// Copy returns a deep copy of the stats
+func (s *SupplyStats) Copy() *SupplyStats {
+	return &SupplyStats{
+		Supply:          new(big.Int).Set(s.Supply),
+		Minted:          new(big.Int).Set(s.Minted),
+		Burned:          new(big.Int).Set(s.Burned),
+		DemurrageBurned: new(big.Int).Set(s.DemurrageBurned),
+		Accounts:        s.Accounts,
+	}
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"math/big"
+
 	"bitbucket.org/ventureslash/go-ibft"
 )
 
@@ -15,6 +17,23 @@ const (
 type Receipt struct {
 	TxHash ibft.Hash
 	Status uint64
+	// Logs detail the effects of a successful transaction: the money created
+	// or destroyed, and the outputs paid by a batch transfer
+	Logs []*ReceiptLog `rlp:"tail"`
+}
+
+// Kinds of receipt logs
+const (
+	LogMint   = uint8(1)
+	LogBurn   = uint8(2)
+	LogOutput = uint8(3)
+)
+
+// ReceiptLog records an effect of a transaction on an account
+type ReceiptLog struct {
+	Kind    uint8
+	Account ibft.Address // credited by a mint or an output, debited by a burn
+	Amount  *big.Int
 }
 
 // Receipts is an array of Receipt
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ChainID identifies the network in transaction signatures, so that a
+// transaction signed for a test network cannot be replayed on the main one
+var ChainID = big.NewInt(1)
+
+// ErrInvalidSig is returned when the signer of a transaction cannot be
+// recovered
+var ErrInvalidSig = errors.New("invalid transaction signature")
+
+// SigningHash returns the hash signed by the sender of a transaction: the
+// keccak256 hash of the RLP list [ChainID, From, To, Amount] for legacy
+// transfers, and of [ChainID, Type, payload fields but the signature] for
+// typed transactions
+func (s *Transaction) SigningHash() ibft.Hash {
+	switch s.Type {
+	case MintTxType:
+		return ibft.RlpHash([]interface{}{ChainID, s.Type, s.To, s.Amount, s.Nonce})
+	case BurnTxType:
+		return ibft.RlpHash([]interface{}{ChainID, s.Type, s.From, s.Amount})
+	case BatchTxType:
+		return ibft.RlpHash([]interface{}{ChainID, s.Type, s.From, s.Outputs})
+	case PaymentTxType:
+		return ibft.RlpHash([]interface{}{ChainID, s.Type, s.From, s.To, s.Amount, s.Reference})
+	default:
+		return ibft.RlpHash([]interface{}{ChainID, s.From, s.To, s.Amount})
+	}
+}
+
+// SignTx returns a copy of tx signed with key. The signature is the 65 bytes
+// [R || S || V] secp256k1 signature of the signing hash, with V in {0, 1}.
+func SignTx(tx *Transaction, key *ecdsa.PrivateKey) (*Transaction, error) {
+	signed := tx.Copy()
+	sig, err := crypto.Sign(signed.SigningHash().Bytes(), key)
+	if err != nil {
+		return nil, err
+	}
+	signed.Signature = sig
+	return signed, nil
+}
+
+// Sender returns the address that signed tx
+func Sender(tx *Transaction) (ibft.Address, error) {
+	if len(tx.Signature) != 65 {
+		return ibft.Address{}, ErrInvalidSig
+	}
+	pub, err := crypto.SigToPub(tx.SigningHash().Bytes(), tx.Signature)
+	if err != nil {
+		return ibft.Address{}, ErrInvalidSig
+	}
+	addr := ibft.Address{}
+	addr.FromBytes(crypto.PubkeyToAddress(*pub).Bytes())
+	return addr, nil
+}
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"math/big"
+
+	"bitbucket.org/ventureslash/go-ibft"
+)
+
+// AccountState is the balance held by an account at a given block
+type AccountState struct {
+	Address ibft.Address `json:"address"`
+	Balance *big.Int     `json:"balance"`
+}
+
+// StateDiff lists the accounts modified by a block along with their new
+// balance
+type StateDiff []*AccountState
+
+// StateSnapshot is the full state of the accounts at a given block, with the
+// IDs of the mints committed up to it
+type StateSnapshot struct {
+	Number   *big.Int
+	Accounts StateDiff
+	Mints    []ibft.Hash `rlp:"tail"`
+}
@@ -1,19 +1,152 @@
 package types
 
 import (
+	"errors"
+	"io"
 	"math/big"
 
 	"bitbucket.org/ventureslash/go-ibft"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
-// Transaction represents a transaction sent over the network
+// Transaction types. Legacy transfers are encoded as the RLP list [From, To,
+// Amount, Signature], as before transactions were typed. The other types are
+// encoded as an RLP string holding the type byte followed by the RLP payload
+// of the type, so that new types never change the encoding of the existing
+// ones.
+const (
+	LegacyTxType = uint8(0)
+	// MintTxType creates money, approved by the issuers. Its payload is
+	// [To, Amount, Nonce, Signatures].
+	MintTxType = uint8(1)
+	// BurnTxType destroys money of its sender. Its payload is [From, Amount,
+	// Signature].
+	BurnTxType = uint8(2)
+	// BatchTxType pays several recipients from one sender, atomically. Its
+	// payload is [From, [[To, Amount]...], Signature].
+	BatchTxType = uint8(3)
+	// PaymentTxType is a transfer carrying a reference, such as the invoice
+	// it pays. Its payload is [From, To, Amount, Reference, Signature].
+	PaymentTxType = uint8(4)
+)
+
+const (
+	// MaxBatchOutputs is the maximum number of outputs of a batch transfer
+	MaxBatchOutputs = 512
+	// MaxReferenceLength is the maximum length in bytes of the reference of
+	// a payment
+	MaxReferenceLength = 64
+)
+
+var (
+	// ErrTxTypeNotSupported is returned when decoding a transaction of an
+	// unknown type
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	// ErrBatchSize is returned for a batch transfer without outputs or with
+	// more than MaxBatchOutputs
+	ErrBatchSize = errors.New("invalid number of batch outputs")
+	// ErrReferenceTooLong is returned for a payment whose reference exceeds
+	// MaxReferenceLength
+	ErrReferenceTooLong = errors.New("payment reference too long")
+	// ErrInvalidAmount is returned for a transfer, a payment or a batch
+	// output whose amount is not positive
+	ErrInvalidAmount = errors.New("transfer amount must be positive")
+)
+
+// Transaction represents a transaction sent over the network. The fields
+// that are not part of the payload of its type are left empty, but for the
+// Amount of a batch transfer, which is the total of its outputs. The Nonce of
+// a mint tells apart the mints of the same amount to the same account.
 type Transaction struct {
+	Type      uint8        `json:"type"`
 	From      ibft.Address `json:"from"`
 	To        ibft.Address `json:"to"`
 	Amount    *big.Int     `json:"amount"`
+	Outputs   []*TxOutput  `json:"outputs,omitempty"`
+	Reference []byte       `json:"reference,omitempty"`
+	Nonce     uint64       `json:"nonce,omitempty"`
 	Signature []byte       `json:"signature"`
 }
 
+// TxOutput is a payment of a batch transfer
+type TxOutput struct {
+	To     ibft.Address `json:"to"`
+	Amount *big.Int     `json:"amount"`
+}
+
+type legacyTx struct {
+	From      ibft.Address
+	To        ibft.Address
+	Amount    *big.Int
+	Signature []byte
+}
+
+type mintTx struct {
+	To         ibft.Address
+	Amount     *big.Int
+	Nonce      uint64
+	Signatures []byte
+}
+
+type burnTx struct {
+	From      ibft.Address
+	Amount    *big.Int
+	Signature []byte
+}
+
+type batchTx struct {
+	From      ibft.Address
+	Outputs   []*TxOutput
+	Signature []byte
+}
+
+type paymentTx struct {
+	From      ibft.Address
+	To        ibft.Address
+	Amount    *big.Int
+	Reference []byte
+	Signature []byte
+}
+
+// NewPayment returns an unsigned transfer of amount from the account from to
+// the account to, carrying reference
+func NewPayment(from ibft.Address, to ibft.Address, amount *big.Int, reference []byte) *Transaction {
+	return &Transaction{Type: PaymentTxType, From: from, To: to, Amount: amount, Reference: reference}
+}
+
+// NewBatch returns an unsigned batch transfer paying outputs from the account
+// from
+func NewBatch(from ibft.Address, outputs []*TxOutput) *Transaction {
+	total := new(big.Int)
+	for _, output := range outputs {
+		total.Add(total, output.Amount)
+	}
+	return &Transaction{Type: BatchTxType, From: from, Amount: total, Outputs: outputs}
+}
+
+// Payments returns the recipients paid by tx and their amounts: the outputs
+// of a batch transfer, or the single recipient of the other types
+func (s *Transaction) Payments() []*TxOutput {
+	if s.Type == BatchTxType {
+		return s.Outputs
+	}
+	return []*TxOutput{{To: s.To, Amount: s.Amount}}
+}
+
+// CheckAmounts returns ErrInvalidAmount if an amount paid by a transfer, a
+// payment or a batch transfer is not positive
+func (s *Transaction) CheckAmounts() error {
+	switch s.Type {
+	case LegacyTxType, BatchTxType, PaymentTxType:
+		for _, payment := range s.Payments() {
+			if payment.Amount == nil || payment.Amount.Sign() <= 0 {
+				return ErrInvalidAmount
+			}
+		}
+	}
+	return nil
+}
+
 // NewTransaction initializes a transaction
 func NewTransaction(from ibft.Address, to ibft.Address, amount *big.Int) *Transaction {
 	return &Transaction{
@@ -23,6 +156,116 @@ func NewTransaction(from ibft.Address, to ibft.Address, amount *big.Int) *Transa
 	}
 }
 
+// Copy returns a copy of the transaction
+func (s *Transaction) Copy() *Transaction {
+	tx := *s
+	tx.Amount = new(big.Int).Set(s.Amount)
+	tx.Signature = append([]byte{}, s.Signature...)
+	if s.Reference != nil {
+		tx.Reference = append([]byte{}, s.Reference...)
+	}
+	if s.Outputs != nil {
+		tx.Outputs = make([]*TxOutput, len(s.Outputs))
+		for i, output := range s.Outputs {
+			tx.Outputs[i] = &TxOutput{To: output.To, Amount: new(big.Int).Set(output.Amount)}
+		}
+	}
+	return &tx
+}
+
+// PoolCopy returns the copy of the transaction kept in the pending pool and
+// included in blocks. Every node checks the approvals of a mint when
+// processing its block, so mints keep their signatures. Payments keep theirs
+// as the proof that the sender paid with the reference. The signature of the
+// other types is dropped.
+func (s *Transaction) PoolCopy() *Transaction {
+	tx := s.Copy()
+	if s.Type != MintTxType && s.Type != PaymentTxType {
+		tx.Signature = nil
+	}
+	return tx
+}
+
+// EncodeRLP implements rlp.Encoder, encoding the typed envelope
+func (s *Transaction) EncodeRLP(w io.Writer) error {
+	var payload interface{}
+	switch s.Type {
+	case LegacyTxType:
+		return rlp.Encode(w, &legacyTx{s.From, s.To, s.Amount, s.Signature})
+	case MintTxType:
+		payload = &mintTx{s.To, s.Amount, s.Nonce, s.Signature}
+	case BurnTxType:
+		payload = &burnTx{s.From, s.Amount, s.Signature}
+	case BatchTxType:
+		payload = &batchTx{s.From, s.Outputs, s.Signature}
+	case PaymentTxType:
+		payload = &paymentTx{s.From, s.To, s.Amount, s.Reference, s.Signature}
+	default:
+		return ErrTxTypeNotSupported
+	}
+	data, err := rlp.EncodeToBytes(payload)
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, append([]byte{s.Type}, data...))
+}
+
+// DecodeRLP implements rlp.Decoder, decoding legacy transfers and typed
+// envelopes
+func (s *Transaction) DecodeRLP(stream *rlp.Stream) error {
+	kind, _, err := stream.Kind()
+	if err != nil {
+		return err
+	}
+	if kind == rlp.List {
+		tx := legacyTx{}
+		if err := stream.Decode(&tx); err != nil {
+			return err
+		}
+		*s = Transaction{Type: LegacyTxType, From: tx.From, To: tx.To, Amount: tx.Amount, Signature: tx.Signature}
+		return nil
+	}
+
+	envelope, err := stream.Bytes()
+	if err != nil {
+		return err
+	}
+	if len(envelope) == 0 {
+		return errors.New("empty transaction envelope")
+	}
+	switch envelope[0] {
+	case MintTxType:
+		tx := mintTx{}
+		if err := rlp.DecodeBytes(envelope[1:], &tx); err != nil {
+			return err
+		}
+		*s = Transaction{Type: MintTxType, From: MintAccount, To: tx.To, Amount: tx.Amount, Nonce: tx.Nonce, Signature: tx.Signatures}
+	case BurnTxType:
+		tx := burnTx{}
+		if err := rlp.DecodeBytes(envelope[1:], &tx); err != nil {
+			return err
+		}
+		*s = Transaction{Type: BurnTxType, From: tx.From, To: MintAccount, Amount: tx.Amount, Signature: tx.Signature}
+	case BatchTxType:
+		tx := batchTx{}
+		if err := rlp.DecodeBytes(envelope[1:], &tx); err != nil {
+			return err
+		}
+		*s = *NewBatch(tx.From, tx.Outputs)
+		s.Signature = tx.Signature
+	case PaymentTxType:
+		tx := paymentTx{}
+		if err := rlp.DecodeBytes(envelope[1:], &tx); err != nil {
+			return err
+		}
+		*s = *NewPayment(tx.From, tx.To, tx.Amount, tx.Reference)
+		s.Signature = tx.Signature
+	default:
+		return ErrTxTypeNotSupported
+	}
+	return nil
+}
+
 // Transactions is a Transaction slice type for basic sorting.
 type Transactions []*Transaction
 
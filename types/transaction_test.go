@@ -0,0 +1,63 @@
+package types_test
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestTransactionEnvelope(t *testing.T) {
+	alice, bob := ibft.Address{1}, ibft.Address{2}
+	legacy := types.NewTransaction(alice, bob, big.NewInt(10))
+	legacy.Signature = []byte{1, 2, 3}
+
+	// Legacy transfers keep the encoding of the blocks stored before
+	// transactions were typed
+	enc, err := rlp.EncodeToBytes(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := rlp.EncodeToBytes([]interface{}{alice, bob, big.NewInt(10), []byte{1, 2, 3}})
+	if err != nil {
+		panic(err)
+	}
+	if !bytes.Equal(enc, want) {
+		t.Fatalf("legacy transfer encoded as %x, want %x", enc, want)
+	}
+
+	mint := types.NewMint(bob, big.NewInt(5))
+	mint.Signature = []byte{4}
+	burn := types.NewBurn(alice, big.NewInt(3))
+	batch := types.NewBatch(alice, []*types.TxOutput{{To: bob, Amount: big.NewInt(1)}, {To: bob, Amount: big.NewInt(2)}})
+	payment := types.NewPayment(alice, bob, big.NewInt(7), []byte("INV-42"))
+	txs := types.Transactions{legacy, mint, burn, batch, payment}
+	enc, err = rlp.EncodeToBytes(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := types.Transactions{}
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for i := range txs {
+		if txs[i].Signature == nil {
+			txs[i].Signature = []byte{}
+		}
+		if !reflect.DeepEqual(decoded[i], txs[i]) || decoded[i].Hash() != txs[i].Hash() {
+			t.Errorf("transaction %d decoded as %+v, want %+v", i, decoded[i], txs[i])
+		}
+	}
+
+	unknown, err := rlp.EncodeToBytes([]byte{0x7f, 0xc0})
+	if err != nil {
+		panic(err)
+	}
+	if err := rlp.DecodeBytes(unknown, &types.Transaction{}); err != types.ErrTxTypeNotSupported {
+		t.Fatalf("got %v decoding an unknown type, want ErrTxTypeNotSupported", err)
+	}
+}
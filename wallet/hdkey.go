@@ -0,0 +1,189 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	eth "github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// HardenedKeyStart is the index of the first hardened child key
+const HardenedKeyStart = 0x80000000
+
+// DefaultBaseDerivationPath is the BIP-44 path under which accounts are
+// derived. Addresses are computed like Ethereum ones, so the Ethereum coin
+// type is used and mnemonics recover the same accounts in Ethereum wallets.
+var DefaultBaseDerivationPath = DerivationPath{HardenedKeyStart + 44, HardenedKeyStart + 60, HardenedKeyStart + 0, 0}
+
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	errInvalidChild    = errors.New("derived key is invalid, use the next index")
+	masterKeySecret    = []byte("Bitcoin seed")
+)
+
+// DerivationPath is a BIP-32 derivation path, such as m/44'/60'/0'/0/1
+type DerivationPath []uint32
+
+// ParseDerivationPath parses a derivation path. Hardened indexes are marked
+// with ' or h.
+func ParseDerivationPath(path string) (DerivationPath, error) {
+	components := strings.Split(strings.TrimSpace(path), "/")
+	if components[0] != "m" {
+		return nil, fmt.Errorf("derivation path %q does not start with m", path)
+	}
+	result := DerivationPath{}
+	for _, component := range components[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(component, "'") || strings.HasSuffix(component, "h") {
+			offset = HardenedKeyStart
+			component = component[:len(component)-1]
+		}
+		index, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid component %q in derivation path %q", component, path)
+		}
+		result = append(result, uint32(index)+offset)
+	}
+	return result, nil
+}
+
+// Child returns the path of the child index of p
+func (p DerivationPath) Child(index uint32) DerivationPath {
+	child := make(DerivationPath, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, index)
+}
+
+func (p DerivationPath) String() string {
+	result := "m"
+	for _, index := range p {
+		if index >= HardenedKeyStart {
+			result += fmt.Sprintf("/%d'", index-HardenedKeyStart)
+		} else {
+			result += fmt.Sprintf("/%d", index)
+		}
+	}
+	return result
+}
+
+// ExtendedKey is a BIP-32 private key with its chain code
+type ExtendedKey struct {
+	key       []byte
+	chainCode []byte
+}
+
+// NewMasterKey returns the BIP-32 master key of a seed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("seed must be between 128 and 512 bits")
+	}
+	mac := hmac.New(sha512.New, masterKeySecret)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(eth.S256().Params().N) >= 0 {
+		return nil, errors.New("seed does not give a valid master key")
+	}
+	return &ExtendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// Child derives the child key at index. Indexes from HardenedKeyStart give
+// hardened keys.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= HardenedKeyStart {
+		data = append(data, 0)
+		data = append(data, k.key...)
+	} else {
+		key, err := k.PrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, eth.CompressPubkey(&key.PublicKey)...)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := eth.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errInvalidChild
+	}
+	child := il.Add(il, new(big.Int).SetBytes(k.key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	return &ExtendedKey{key: math.PaddedBigBytes(child, 32), chainCode: sum[32:]}, nil
+}
+
+// Derive derives the key at path from k
+func (k *ExtendedKey) Derive(path DerivationPath) (*ExtendedKey, error) {
+	var err error
+	for _, index := range path {
+		if k, err = k.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// PrivateKey returns the secp256k1 private key of k
+func (k *ExtendedKey) PrivateKey() (*ecdsa.PrivateKey, error) {
+	return eth.ToECDSA(k.key)
+}
+
+// ChainCode returns the chain code of k
+func (k *ExtendedKey) ChainCode() []byte {
+	return k.chainCode
+}
+
+// NewMnemonic generates a BIP-39 mnemonic from bits of entropy: 128 bits give
+// 12 words, 256 bits give 24 words
+func NewMnemonic(bits int) (string, error) {
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// MnemonicSeed validates a BIP-39 mnemonic and returns its seed, protected by
+// an optional passphrase
+func MnemonicSeed(mnemonic string, passphrase string) ([]byte, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	return bip39.NewSeed(mnemonic, passphrase), nil
+}
+
+// FromMnemonic recovers the key at path of a BIP-39 mnemonic
+func FromMnemonic(mnemonic string, passphrase string, path DerivationPath) (*ecdsa.PrivateKey, error) {
+	seed, err := MnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	key, err := master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey()
+}
@@ -0,0 +1,124 @@
+package wallet_test
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/wallet"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Test vector 1 of BIP-32
+func TestDeriveBIP32Vector(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	vectors := []struct {
+		path      string
+		key       string
+		chainCode string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", ""},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca", ""},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4", ""},
+		{"m/0h/1/2h/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", ""},
+	}
+
+	master, err := wallet.NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		path, err := wallet.ParseDerivationPath(v.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extended, err := master.Derive(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := extended.PrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(crypto.FromECDSA(key)); got != v.key {
+			t.Errorf("%s: got key %s, want %s", v.path, got, v.key)
+		}
+		if got := hex.EncodeToString(extended.ChainCode()); v.chainCode != "" && got != v.chainCode {
+			t.Errorf("%s: got chain code %s, want %s", v.path, got, v.chainCode)
+		}
+	}
+}
+
+// Test vectors of BIP-39, using the TREZOR passphrase
+func TestMnemonicSeedVectors(t *testing.T) {
+	vectors := []struct {
+		mnemonic string
+		seed     string
+	}{
+		{
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+	}
+	for _, v := range vectors {
+		seed, err := wallet.MnemonicSeed(v.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(seed); got != v.seed {
+			t.Errorf("%q: got seed %s, want %s", v.mnemonic, got, v.seed)
+		}
+	}
+	if _, err := wallet.MnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", ""); err != wallet.ErrInvalidMnemonic {
+		t.Fatalf("got %v for a bad checksum, want ErrInvalidMnemonic", err)
+	}
+}
+
+func TestRecoverFromMnemonic(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	key, err := wallet.FromMnemonic(mnemonic, "", wallet.DefaultBaseDerivationPath.Child(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// First account of the mnemonic in Ethereum wallets
+	if got := hex.EncodeToString(wallet.Address(key).Bytes()); got != "9858effd232b4033e47d90003d41ec34ecaeda94" {
+		t.Fatalf("got address %s", got)
+	}
+
+	mnemonic, err = wallet.NewMnemonic(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	m, err := wallet.NewManager(dir, ibft.Address{})
+	if err != nil {
+		panic(err)
+	}
+	path := wallet.DefaultBaseDerivationPath.Child(3)
+	account, err := m.ImportMnemonic(mnemonic, "extra words", path, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = wallet.FromMnemonic(mnemonic, "extra words", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Address(key) != account.Address {
+		t.Fatal("recovered key differs from the imported account")
+	}
+	if path.String() != "m/44'/60'/0'/0/3" {
+		t.Fatalf("got path %s", path)
+	}
+}
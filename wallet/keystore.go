@@ -0,0 +1,177 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	eth "github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keystoreVersion = 3
+	keystoreCipher  = "aes-256-gcm"
+	keystoreKDF     = "scrypt"
+
+	// StandardScryptN and StandardScryptP make deriving the encryption key
+	// take about a second and 256MB of memory
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	// LightScryptN and LightScryptP make deriving the encryption key take
+	// about 100ms and 4MB of memory
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+// ErrDecrypt is returned when a keystore cannot be opened with a passphrase
+var ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+
+// encryptedKey is the JSON keystore format. It follows the Ethereum v3
+// keystore, with AES-GCM authenticating the ciphertext in place of a MAC.
+type encryptedKey struct {
+	Version int          `json:"version"`
+	ID      string       `json:"id"`
+	Address string       `json:"address"`
+	Crypto  cryptoParams `json:"crypto"`
+}
+
+type cryptoParams struct {
+	Cipher       string       `json:"cipher"`
+	CipherText   string       `json:"ciphertext"`
+	CipherParams cipherParams `json:"cipherparams"`
+	KDF          string       `json:"kdf"`
+	KDFParams    scryptParams `json:"kdfparams"`
+}
+
+type cipherParams struct {
+	Nonce string `json:"nonce"`
+}
+
+type scryptParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	return b
+}
+
+// Address returns the address of the account of a key
+func Address(key *ecdsa.PrivateKey) ibft.Address {
+	addr := ibft.Address{}
+	addr.FromBytes(eth.PubkeyToAddress(key.PublicKey).Bytes())
+	return addr
+}
+
+// EncryptKey encrypts a private key into a JSON keystore with a key derived
+// from passphrase by scrypt
+func EncryptKey(key *ecdsa.PrivateKey, passphrase string, scryptN int, scryptP int) ([]byte, error) {
+	salt := randomBytes(32)
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := randomBytes(gcm.NonceSize())
+	cipherText := gcm.Seal(nil, nonce, eth.FromECDSA(key), nil)
+
+	id := randomBytes(16)
+	return json.MarshalIndent(&encryptedKey{
+		Version: keystoreVersion,
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Address: hex.EncodeToString(Address(key).Bytes()),
+		Crypto: cryptoParams{
+			Cipher:       keystoreCipher,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParams{Nonce: hex.EncodeToString(nonce)},
+			KDF:          keystoreKDF,
+			KDFParams: scryptParams{
+				N:     scryptN,
+				R:     scryptR,
+				P:     scryptP,
+				DKLen: scryptDKLen,
+				Salt:  hex.EncodeToString(salt),
+			},
+		},
+	}, "", "  ")
+}
+
+// DecryptKey opens a JSON keystore with passphrase
+func DecryptKey(data []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	k := &encryptedKey{}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	if k.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", k.Version)
+	}
+	if k.Crypto.Cipher != keystoreCipher || k.Crypto.KDF != keystoreKDF {
+		return nil, fmt.Errorf("unsupported keystore cipher %s or kdf %s", k.Crypto.Cipher, k.Crypto.KDF)
+	}
+
+	// The parameters come from the file: bound the time and memory scrypt
+	// takes to those of the keystores this package writes
+	params := k.Crypto.KDFParams
+	if params.N > StandardScryptN || params.R > scryptR || params.P > LightScryptP || params.DKLen != scryptDKLen {
+		return nil, fmt.Errorf("unsupported keystore scrypt parameters n=%d r=%d p=%d dklen=%d", params.N, params.R, params.P, params.DKLen)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(k.Crypto.CipherParams.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid keystore nonce")
+	}
+	plainText, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return eth.ToECDSA(plainText)
+}
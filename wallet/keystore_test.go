@@ -0,0 +1,122 @@
+package wallet_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-slash-currency/wallet"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEncryptDecryptKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	data, err := wallet.EncryptKey(key, "passphrase", wallet.LightScryptN, wallet.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := wallet.DecryptKey(data, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.D.Cmp(key.D) != 0 {
+		t.Fatal("decrypted key differs from the original one")
+	}
+	if _, err := wallet.DecryptKey(data, "wrong"); err != wallet.ErrDecrypt {
+		t.Fatalf("got %v with a wrong passphrase, want ErrDecrypt", err)
+	}
+}
+
+func TestDecryptKeyScryptBounds(t *testing.T) {
+	key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	data, err := wallet.EncryptKey(key, "passphrase", wallet.LightScryptN, wallet.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A crafted keystore must not make the wallet spend hours or gigabytes
+	// deriving its key
+	for param, value := range map[string]int{"n": 1 << 30, "r": 1 << 20, "p": 1 << 20, "dklen": 1 << 30} {
+		k := map[string]interface{}{}
+		if err := json.Unmarshal(data, &k); err != nil {
+			t.Fatal(err)
+		}
+		k["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})[param] = value
+		crafted, err := json.Marshal(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wallet.DecryptKey(crafted, "passphrase"); err == nil || err == wallet.ErrDecrypt {
+			t.Errorf("got %v for a keystore with %s=%d", err, param, value)
+		}
+	}
+}
+
+func TestNewWalletPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/test.wallet"
+	if _, err := wallet.New(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("wallet created with permissions %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestMigratePEMWallet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	path := dir + "/legacy.wallet"
+	data := pem.EncodeToMemory(&pem.Block{Type: "SLASH WALLET", Bytes: crypto.FromECDSA(key)})
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		panic(err)
+	}
+
+	if err := wallet.Migrate(path, "test passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallet.Migrate(path, "test passphrase"); err == nil {
+		t.Fatal("migrated an encrypted wallet twice")
+	}
+	migrated, err := wallet.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.D.Cmp(key.D) != 0 {
+		t.Fatal("migrated wallet does not hold the original key")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("migrated wallet has permissions %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,252 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	eth "github.com/ethereum/go-ethereum/crypto"
+)
+
+const keystoreExt = ".json"
+
+var (
+	ErrUnknownAccount = errors.New("unknown account")
+	ErrLocked         = errors.New("account is locked")
+	ErrAccountExists  = errors.New("account already exists")
+	ErrValidatorKey   = errors.New("the validator key cannot be used as a spending account")
+	errWrongSender    = errors.New("transaction is not sent by the signing account")
+)
+
+// Account is a spending account held by a Manager
+type Account struct {
+	Address ibft.Address
+	// Keystore file of the account
+	Path string
+}
+
+// Manager holds the spending accounts of a keystore directory, one encrypted
+// keystore file per account. Accounts must be unlocked with their passphrase
+// before signing transactions.
+type Manager struct {
+	dir       string
+	validator ibft.Address
+	scryptN   int
+	scryptP   int
+
+	mu       sync.RWMutex
+	accounts map[ibft.Address]string
+	unlocked map[ibft.Address]*ecdsa.PrivateKey
+}
+
+// NewManager opens the keystore directory dir, creating it if needed.
+// validator is the address of the node validator key, which is kept out of
+// the spending accounts. The zero address disables the check.
+func NewManager(dir string, validator ibft.Address) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		dir:       dir,
+		validator: validator,
+		scryptN:   StandardScryptN,
+		scryptP:   StandardScryptP,
+		accounts:  make(map[ibft.Address]string),
+		unlocked:  make(map[ibft.Address]*ecdsa.PrivateKey),
+	}
+	if *lightKDF {
+		m.scryptN, m.scryptP = LightScryptN, LightScryptP
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), keystoreExt) {
+			continue
+		}
+		addr, err := hex.DecodeString(strings.TrimSuffix(f.Name(), keystoreExt))
+		if err != nil || len(addr) != len(ibft.Address{}) {
+			continue
+		}
+		account := ibft.Address{}
+		account.FromBytes(addr)
+		m.accounts[account] = filepath.Join(dir, f.Name())
+	}
+	return m, nil
+}
+
+// Accounts lists the accounts of the keystore, sorted by address
+func (m *Manager) Accounts() []Account {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	accounts := make([]Account, 0, len(m.accounts))
+	for addr, path := range m.accounts {
+		accounts = append(accounts, Account{Address: addr, Path: path})
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i].Address.Bytes(), accounts[j].Address.Bytes()) < 0
+	})
+	return accounts
+}
+
+// Create generates a new account encrypted with passphrase
+func (m *Manager) Create(passphrase string) (Account, error) {
+	key, err := ecdsa.GenerateKey(eth.S256(), rand.Reader)
+	if err != nil {
+		return Account{}, err
+	}
+	return m.Import(key, passphrase)
+}
+
+// Import adds a private key to the keystore, encrypted with passphrase
+func (m *Manager) Import(key *ecdsa.PrivateKey, passphrase string) (Account, error) {
+	addr := Address(key)
+	if addr == m.validator {
+		return Account{}, ErrValidatorKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[addr]; ok {
+		return Account{}, ErrAccountExists
+	}
+	data, err := EncryptKey(key, passphrase, m.scryptN, m.scryptP)
+	if err != nil {
+		return Account{}, err
+	}
+	path := filepath.Join(m.dir, hex.EncodeToString(addr.Bytes())+keystoreExt)
+	if err := writeFile(path, data); err != nil {
+		return Account{}, err
+	}
+	m.accounts[addr] = path
+	return Account{Address: addr, Path: path}, nil
+}
+
+// ImportKeystore adds an account exported by Export, or any keystore file,
+// re-encrypting it with newPassphrase
+func (m *Manager) ImportKeystore(data []byte, passphrase string, newPassphrase string) (Account, error) {
+	key, err := DecryptKey(data, passphrase)
+	if err != nil {
+		return Account{}, err
+	}
+	return m.Import(key, newPassphrase)
+}
+
+// Export returns the keystore of an account encrypted with newPassphrase
+func (m *Manager) Export(addr ibft.Address, passphrase string, newPassphrase string) ([]byte, error) {
+	key, err := m.decrypt(addr, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptKey(key, newPassphrase, m.scryptN, m.scryptP)
+}
+
+// Delete removes an account from the keystore. The passphrase is required so
+// that an account is not deleted by mistake.
+func (m *Manager) Delete(addr ibft.Address, passphrase string) error {
+	if _, err := m.decrypt(addr, passphrase); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path, ok := m.accounts[addr]
+	if !ok {
+		return ErrUnknownAccount
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	delete(m.accounts, addr)
+	delete(m.unlocked, addr)
+	return nil
+}
+
+// Unlock decrypts an account so that it can sign transactions
+func (m *Manager) Unlock(addr ibft.Address, passphrase string) error {
+	key, err := m.decrypt(addr, passphrase)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unlocked[addr] = key
+	return nil
+}
+
+// Lock removes the decrypted key of an account from memory
+func (m *Manager) Lock(addr ibft.Address) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[addr]; !ok {
+		return ErrUnknownAccount
+	}
+	if key, ok := m.unlocked[addr]; ok {
+		zeroKey(key)
+		delete(m.unlocked, addr)
+	}
+	return nil
+}
+
+// SignTx returns a copy of tx signed by an unlocked account. The transaction
+// must be sent by the account.
+func (m *Manager) SignTx(addr ibft.Address, tx *types.Transaction) (*types.Transaction, error) {
+	m.mu.RLock()
+	key, ok := m.unlocked[addr]
+	_, known := m.accounts[addr]
+	m.mu.RUnlock()
+	if !known {
+		return nil, ErrUnknownAccount
+	}
+	if !ok {
+		return nil, ErrLocked
+	}
+	if tx.From != addr {
+		return nil, errWrongSender
+	}
+
+	return types.SignTx(tx, key)
+}
+
+func (m *Manager) decrypt(addr ibft.Address, passphrase string) (*ecdsa.PrivateKey, error) {
+	m.mu.RLock()
+	path, ok := m.accounts[addr]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownAccount
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKey(data, passphrase)
+}
+
+// zeroKey overwrites the private part of a key
+func zeroKey(key *ecdsa.PrivateKey) {
+	b := key.D.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// ImportMnemonic recovers the account at path of a BIP-39 mnemonic into the
+// keystore, encrypted with passphrase
+func (m *Manager) ImportMnemonic(mnemonic string, mnemonicPassphrase string, path DerivationPath, passphrase string) (Account, error) {
+	key, err := FromMnemonic(mnemonic, mnemonicPassphrase, path)
+	if err != nil {
+		return Account{}, err
+	}
+	return m.Import(key, passphrase)
+}
@@ -0,0 +1,130 @@
+package wallet_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"bitbucket.org/ventureslash/go-ibft"
+	"bitbucket.org/ventureslash/go-slash-currency/types"
+	"bitbucket.org/ventureslash/go-slash-currency/wallet"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func newTestManager(validator ibft.Address) (*wallet.Manager, string) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		panic(err)
+	}
+	m, err := wallet.NewManager(dir, validator)
+	if err != nil {
+		panic(err)
+	}
+	return m, dir
+}
+
+func TestManagerSignTx(t *testing.T) {
+	m, dir := newTestManager(ibft.Address{})
+	defer os.RemoveAll(dir)
+
+	account, err := m.Create("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTransaction(account.Address, ibft.Address{1}, big.NewInt(10))
+	if _, err := m.SignTx(account.Address, tx); err != wallet.ErrLocked {
+		t.Fatalf("got %v signing with a locked account, want ErrLocked", err)
+	}
+	if err := m.Unlock(account.Address, "wrong"); err != wallet.ErrDecrypt {
+		t.Fatalf("got %v unlocking with a wrong passphrase, want ErrDecrypt", err)
+	}
+	if err := m.Unlock(account.Address, "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := m.SignTx(account.Address, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := types.Sender(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != account.Address {
+		t.Fatalf("transaction signed by %v, want %v", signer, account.Address)
+	}
+	if _, err := m.SignTx(account.Address, types.NewTransaction(ibft.Address{2}, ibft.Address{1}, big.NewInt(10))); err == nil {
+		t.Fatal("signed a transaction sent by another account")
+	}
+
+	if err := m.Lock(account.Address); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SignTx(account.Address, tx); err != wallet.ErrLocked {
+		t.Fatalf("got %v signing after Lock, want ErrLocked", err)
+	}
+}
+
+func TestManagerAccounts(t *testing.T) {
+	validator, err := ecdsa.GenerateKey(crypto.S256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	m, dir := newTestManager(wallet.Address(validator))
+	defer os.RemoveAll(dir)
+
+	if _, err := m.Import(validator, "passphrase"); err != wallet.ErrValidatorKey {
+		t.Fatalf("got %v importing the validator key, want ErrValidatorKey", err)
+	}
+	first, err := m.Create("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.Create("second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Accounts are found again when the keystore is reopened
+	reopened, err := wallet.NewManager(dir, wallet.Address(validator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accounts := reopened.Accounts(); len(accounts) != 2 {
+		t.Fatalf("got %d accounts, want 2", len(accounts))
+	}
+
+	exported, err := m.Export(first.Address, "first", "exported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(first.Address, "wrong"); err != wallet.ErrDecrypt {
+		t.Fatalf("got %v deleting with a wrong passphrase, want ErrDecrypt", err)
+	}
+	if err := m.Delete(first.Address, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if accounts := m.Accounts(); len(accounts) != 1 || accounts[0].Address != second.Address {
+		t.Fatalf("unexpected accounts after delete: %v", accounts)
+	}
+	if _, err := os.Stat(first.Path); !os.IsNotExist(err) {
+		t.Fatal("keystore file of a deleted account still exists")
+	}
+
+	imported, err := m.ImportKeystore(exported, "exported", "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Address != first.Address {
+		t.Fatalf("imported account %v, want %v", imported.Address, first.Address)
+	}
+	if _, err := m.ImportKeystore(exported, "exported", "imported"); err != wallet.ErrAccountExists {
+		t.Fatalf("got %v importing an account twice, want ErrAccountExists", err)
+	}
+	if err := m.Unlock(first.Address, "imported"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,22 +5,47 @@ import (
 	"crypto/rand"
 	"encoding/pem"
 	"errors"
+	"flag"
 	"fmt"
-	eth "github.com/ethereum/go-ethereum/crypto"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+
+	eth "github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/term"
 )
 
+// PassphraseEnv is the environment variable holding the wallet passphrase
+const PassphraseEnv = "SLASH_WALLET_PASSWORD"
+
+var (
+	passwordFile = flag.String("wallet-password-file", "", "file holding the wallet passphrase (default: $"+PassphraseEnv+" or prompt)")
+	lightKDF     = flag.Bool("light-kdf", false, "encrypt wallets with less memory and CPU, at the cost of security")
+
+	errNoPassphrase       = errors.New("no wallet passphrase: set $" + PassphraseEnv + " or -wallet-password-file")
+	errPassphraseMismatch = errors.New("passphrases do not match")
+	errNotPEM             = errors.New("wallet is not a PEM wallet")
+	errWalletExists       = errors.New("a wallet already exists at this path")
+)
+
+// New loads the wallet at path, or creates an encrypted one when it does not
+// exist. Legacy PEM wallets are still loaded but should be converted with
+// Migrate.
 func New(path string) (*ecdsa.PrivateKey, error) {
 	// Check if wallet exists at specified path
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		// The wallet needs to be generated
-		fmt.Println("Creating new wallet at " + path)
+		fmt.Fprintln(os.Stderr, "Creating new wallet at "+path)
+		passphrase, err := Passphrase(true)
+		if err != nil {
+			return nil, err
+		}
 		key, err := ecdsa.GenerateKey(eth.S256(), rand.Reader)
 		if err != nil {
 			return nil, err
 		}
-		err = savePEMKey(path, key)
+		err = saveKey(path, key, passphrase)
 		if err != nil {
 			return nil, err
 		}
@@ -28,11 +53,101 @@ func New(path string) (*ecdsa.PrivateKey, error) {
 	}
 
 	// a file exists at specified path
-	return loadPEMKey(path)
+	if isPEM(path) {
+		fmt.Fprintln(os.Stderr, "Warning: the wallet at "+path+" is not encrypted, convert it with migrate-wallet")
+		return loadPEMKey(path)
+	}
+	passphrase, err := Passphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	return loadKey(path, passphrase)
+}
+
+// Create saves key into a new encrypted wallet at path. It fails when a file
+// already exists at path.
+func Create(path string, key *ecdsa.PrivateKey, passphrase string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return errWalletExists
+	}
+	return saveKey(path, key, passphrase)
+}
+
+// Passphrase returns the wallet passphrase, taken from $SLASH_WALLET_PASSWORD,
+// the -wallet-password-file file or prompted on the terminal. Prompted
+// passphrases are asked twice when confirm is set.
+func Passphrase(confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok {
+		return passphrase, nil
+	}
+	if *passwordFile != "" {
+		data, err := ioutil.ReadFile(*passwordFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errNoPassphrase
+	}
+	passphrase, err := prompt(fd, "Wallet passphrase: ")
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	repeated, err := prompt(fd, "Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if repeated != passphrase {
+		return "", errPassphraseMismatch
+	}
+	return passphrase, nil
+}
+
+func prompt(fd int, msg string) (string, error) {
+	fmt.Fprint(os.Stderr, msg)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(passphrase), err
+}
+
+// Migrate converts the PEM wallet at path into an encrypted keystore
+func Migrate(path string, passphrase string) error {
+	if !isPEM(path) {
+		return errNotPEM
+	}
+	key, err := loadPEMKey(path)
+	if err != nil {
+		return err
+	}
+	if err := saveKey(path, key, passphrase); err != nil {
+		return err
+	}
+
+	// Make sure the wallet can be opened before reporting success
+	migrated, err := loadKey(path, passphrase)
+	if err != nil {
+		return err
+	}
+	if migrated.D.Cmp(key.D) != 0 {
+		return errors.New("migrated wallet does not hold the original key")
+	}
+	return nil
+}
+
+func isPEM(path string) bool {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	block, _ := pem.Decode(bytes)
+	return block != nil
 }
 
 func loadPEMKey(path string) (*ecdsa.PrivateKey, error) {
-	fmt.Println("Loading wallet at " + path)
+	fmt.Fprintln(os.Stderr, "Loading wallet at "+path)
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -49,28 +164,45 @@ func loadPEMKey(path string) (*ecdsa.PrivateKey, error) {
 	return key, nil
 }
 
-func savePEMKey(fileName string, key *ecdsa.PrivateKey) error {
-	outFile, err := os.Create(fileName)
+func loadKey(path string, passphrase string) (*ecdsa.PrivateKey, error) {
+	fmt.Fprintln(os.Stderr, "Loading wallet at "+path)
+	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer outFile.Close()
+	return DecryptKey(bytes, passphrase)
+}
 
-	//bytes, err := x509.MarshalECPrivateKey(key)
-	bytes := eth.FromECDSA(key)
+// saveKey encrypts a key into a keystore file readable by its owner only
+func saveKey(path string, key *ecdsa.PrivateKey, passphrase string) error {
+	scryptN, scryptP := StandardScryptN, StandardScryptP
+	if *lightKDF {
+		scryptN, scryptP = LightScryptN, LightScryptP
+	}
+	data, err := EncryptKey(key, passphrase, scryptN, scryptP)
 	if err != nil {
 		return err
 	}
+	return writeFile(path, data)
+}
 
-	var privateKey = &pem.Block{
-		Type:  "SLASH WALLET",
-		Bytes: bytes,
-	}
-
-	err = pem.Encode(outFile, privateKey)
+// writeFile atomically replaces the content of a file, with 0600 permissions
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
 	if err != nil {
 		return err
 	}
-
-	return nil
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
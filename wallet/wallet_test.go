@@ -3,6 +3,7 @@ package wallet_test
 import (
 	"bitbucket.org/ventureslash/go-slash-currency/wallet"
 	"crypto/ecdsa"
+	"flag"
 	"github.com/ethereum/go-ethereum/crypto"
 	"io/ioutil"
 	"os"
@@ -10,6 +11,11 @@ import (
 	"testing"
 )
 
+func init() {
+	os.Setenv(wallet.PassphraseEnv, "test passphrase")
+	flag.Set("light-kdf", "true")
+}
+
 func sign(data []byte, privkey *ecdsa.PrivateKey) ([]byte, error) {
 	hashData := crypto.Keccak256([]byte(data))
 	return crypto.Sign(hashData, privkey)